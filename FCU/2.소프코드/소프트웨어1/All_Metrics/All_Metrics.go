@@ -0,0 +1,14 @@
+// All_Metrics는 기본 제공 지표 패키지를 모두 불러와 Metric_Registry.Default에 등록되도록 합니다.
+// 새 지표를 추가할 때는 Metric_Registry.Metric을 구현한 패키지를 만들고 init()에서 등록한 뒤,
+// 여기에 import 한 줄만 추가하면 됩니다(main은 수정하지 않습니다).
+package All_Metrics
+
+import (
+	_ "FCU_Tools/M1"
+	_ "FCU_Tools/M2"
+	_ "FCU_Tools/M3"
+	_ "FCU_Tools/M4"
+	_ "FCU_Tools/M5"
+	_ "FCU_Tools/M6"
+	_ "FCU_Tools/SWC_Dependence"
+)
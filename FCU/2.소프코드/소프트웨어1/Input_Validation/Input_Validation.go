@@ -0,0 +1,435 @@
+// Input_Validation.go
+package Input_Validation
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"FCU_Tools/Public_data"
+	"FCU_Tools/SWC_Dependence"
+)
+
+// 문제의 심각도
+//   - 오류: 분석 결과가 틀어지거나 분석을 진행할 수 없는 문제(예: 정수가 아닌 Layer가 0으로 처리됨)
+//   - 경고: 해당 행이 분석에서 제외되거나 기본값으로 처리되는 문제
+const (
+	SeverityError   = "오류"
+	SeverityWarning = "경고"
+)
+
+// Issue는 입력 파일의 문제 하나입니다. Line이 0이면 파일 전체에 대한 문제입니다.
+type Issue struct {
+	File     string
+	Line     int
+	Severity string
+	Message  string
+}
+
+// Report는 모든 입력 파일의 검증 결과입니다.
+type Report struct {
+	Issues []Issue
+}
+
+func (r *Report) add(file string, line int, severity, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{
+		File:     file,
+		Line:     line,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Count는 심각도가 severity인 문제의 개수를 반환합니다.
+func (r Report) Count(severity string) int {
+	n := 0
+	for _, is := range r.Issues {
+		if is.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// HasErrors는 오류 수준의 문제가 하나라도 있으면 true를 반환합니다.
+func (r Report) HasErrors() bool {
+	return r.Count(SeverityError) > 0
+}
+
+// Print는 파일/행 번호 순서로 문제 목록과 합계를 w에 출력합니다.
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintln(w, "========== 입력 검증 결과 ==========")
+	for _, is := range r.Issues {
+		icon := "⚠️"
+		if is.Severity == SeverityError {
+			icon = "❌"
+		}
+		loc := is.File
+		if is.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", is.File, is.Line)
+		}
+		fmt.Fprintf(w, "%s %s %s: %s\n", icon, is.Severity, loc, is.Message)
+	}
+	fmt.Fprintf(w, "합계: 오류 %d, 경고 %d\n", r.Count(SeverityError), r.Count(SeverityWarning))
+}
+
+// ValidateInputs는 cfg에 지정된 asw.csv, component_info.csv, rq_versus_component.csv, complexity.json을 검사합니다.
+// 분석은 수행하지 않으므로 전체 분석 전에 단독으로 실행할 수 있습니다.
+func ValidateInputs(cfg *Public_data.RunConfig) Report {
+	var rep Report
+
+	components := validateComponentInfo(&rep, cfg.M3component_infoxlsxPath)
+	validateASW(&rep, cfg.ConnectorFilePath, cfg.ASWColumns, cfg.NMPolicy, components)
+	reqs := validateRqVersusComponent(&rep, cfg.M2RqExcelPath, components)
+	validateComplexity(&rep, cfg.M2ComplexityJsonPath, reqs)
+
+	// 파일 검사 순서는 유지하고, 같은 파일 안에서는 행 번호 순으로 정렬합니다.
+	fileOrder := make(map[string]int)
+	for _, is := range rep.Issues {
+		if _, ok := fileOrder[is.File]; !ok {
+			fileOrder[is.File] = len(fileOrder)
+		}
+	}
+	sort.SliceStable(rep.Issues, func(i, j int) bool {
+		a, b := rep.Issues[i], rep.Issues[j]
+		if fileOrder[a.File] != fileOrder[b.File] {
+			return fileOrder[a.File] < fileOrder[b.File]
+		}
+		return a.Line < b.Line
+	})
+	return rep
+}
+
+// CSV 파일을 읽습니다. 파일이 없거나 읽을 수 없으면 오류를 기록하고 ok=false를 반환합니다.
+// lines[i]는 rows[i]가 시작하는 파일의 행 번호입니다(빈 줄을 건너뛰거나 따옴표 안의 줄바꿈이 있어도 정확함).
+func readCSV(rep *Report, path, name string) (rows [][]string, lines []int, ok bool) {
+	if strings.TrimSpace(path) == "" {
+		rep.add(name, 0, SeverityError, "경로가 설정되지 않았습니다")
+		return nil, nil, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		rep.add(name, 0, SeverityError, "파일을 열 수 없습니다: %v", err)
+		return nil, nil, false
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rep.add(name, 0, SeverityError, "CSV 형식 오류: %v", err)
+			return nil, nil, false
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	return rows, lines, true
+}
+
+// ======================== component_info.csv ========================
+// 열: Component, Manager, Layer, ASILSplit (M3/M4는 1~3열, M5는 1열과 4열을 사용)
+// 반환값: 컴포넌트 이름 집합(다른 파일과의 교차 검사에 사용)
+func validateComponentInfo(rep *Report, path string) map[string]bool {
+	name := fileLabel(path, "component_info.csv")
+	rows, lines, ok := readCSV(rep, path, name)
+	if !ok {
+		return nil
+	}
+	if len(rows) == 0 {
+		rep.add(name, 0, SeverityError, "파일이 비어 있습니다(헤더 행도 없음)")
+		return nil
+	}
+	if len(rows) == 1 {
+		rep.add(name, 0, SeverityWarning, "헤더만 있고 데이터 행이 없습니다")
+	}
+
+	components := make(map[string]bool)
+	firstLine := make(map[string]int)
+	for i, row := range rows[1:] {
+		line := lines[i+1]
+		if isBlankRow(row) {
+			continue
+		}
+		if len(row) < 3 {
+			rep.add(name, line, SeverityWarning, "열이 %d개뿐이라 M3/M4/M5에서 제외됩니다(Component, Manager, Layer, ASILSplit 필요)", len(row))
+			continue
+		}
+		comp := strings.TrimSpace(row[0])
+		if comp == "" {
+			rep.add(name, line, SeverityWarning, "Component 이름이 비어 있습니다")
+			continue
+		}
+		if prev, dup := firstLine[comp]; dup {
+			rep.add(name, line, SeverityWarning, "컴포넌트 %s가 중복되었습니다(처음: %d행). 마지막 행의 값이 사용됩니다", comp, prev)
+		} else {
+			firstLine[comp] = line
+		}
+		components[comp] = true
+
+		if strings.TrimSpace(row[1]) == "" {
+			rep.add(name, line, SeverityWarning, "컴포넌트 %s의 Manager가 비어 있습니다", comp)
+		}
+		layer := strings.TrimSpace(row[2])
+		if _, err := strconv.Atoi(layer); err != nil {
+			rep.add(name, line, SeverityError, "컴포넌트 %s의 Layer %q를 정수로 해석할 수 없습니다(M3/M4에서 0으로 처리됨)", comp, layer)
+		}
+		if len(row) < 4 {
+			rep.add(name, line, SeverityWarning, "컴포넌트 %s에 ASILSplit 열이 없어 M5에서 제외됩니다", comp)
+			continue
+		}
+		switch split := strings.TrimSpace(row[3]); split {
+		case "Y", "N":
+		default:
+			rep.add(name, line, SeverityWarning, "컴포넌트 %s의 ASILSplit %q는 Y/N이 아니어서 N으로 처리됩니다", comp, split)
+		}
+	}
+	return components
+}
+
+// ======================== asw.csv ========================
+func validateASW(rep *Report, path string, cols Public_data.ASWColumns, nmPolicy string, components map[string]bool) {
+	name := fileLabel(path, "asw.csv")
+	if strings.TrimSpace(path) == "" {
+		rep.add(name, 0, SeverityError, "경로가 설정되지 않았습니다")
+		return
+	}
+	rows, err := SWC_Dependence.LoadASWRows(path, cols)
+	if err != nil {
+		rep.add(name, 0, SeverityError, "%v", err)
+		return
+	}
+	if len(rows) == 0 {
+		rep.add(name, 0, SeverityWarning, "헤더만 있고 데이터 행이 없습니다")
+		return
+	}
+
+	type group struct {
+		firstLine  int
+		p, r       int
+		components []string
+	}
+	groups := make(map[string]*group)
+	var groupOrder []string
+	unknown := make(map[string]bool)
+
+	for _, row := range rows {
+		if row.Component == "" && row.PortType == "" && row.DEOP == "" {
+			continue // 빈 행
+		}
+		if row.Component == "" {
+			rep.add(name, row.Line, SeverityWarning, "Component가 비어 있어 이 행은 의존관계 분석에서 제외됩니다")
+			continue
+		}
+		if row.PortType != "P" && row.PortType != "R" {
+			rep.add(name, row.Line, SeverityWarning, "컴포넌트 %s의 PortType %q는 P/R이 아니어서 제외됩니다", row.Component, row.PortType)
+		}
+		if row.DEOP == "" {
+			rep.add(name, row.Line, SeverityWarning, "컴포넌트 %s의 DE/OP가 비어 있어 이 행은 의존관계 분석에서 제외됩니다", row.Component)
+		}
+
+		if !SWC_Dependence.IsSenderReceiver(row.InterfaceType) && !SWC_Dependence.IsClientServer(row.InterfaceType) {
+			rep.add(name, row.Line, SeverityWarning, "컴포넌트 %s의 InterfaceType %q는 S-R/C-S가 아니어서 S-R(P → R 방향)으로 처리됩니다", row.Component, row.InterfaceType)
+		}
+
+		asil := strings.ToUpper(row.ASIL)
+		asil = strings.TrimPrefix(asil, "ASIL-")
+		switch asil {
+		case "QM", "A", "B", "C", "D":
+		default:
+			rep.add(name, row.Line, SeverityWarning, "컴포넌트 %s의 ASIL %q를 알 수 없어 M6에서 제외됩니다(QM, A~D, ASIL-x 형식)", row.Component, row.ASIL)
+		}
+
+		if components != nil && !components[row.Component] && !unknown[row.Component] {
+			unknown[row.Component] = true
+			rep.add(name, row.Line, SeverityWarning, "컴포넌트 %s가 component_info.csv에 없어 M3/M4/M5에서 제외됩니다", row.Component)
+		}
+
+		if row.DEOP == "" || (row.PortType != "P" && row.PortType != "R") {
+			continue
+		}
+		g, ok := groups[row.DEOP]
+		if !ok {
+			g = &group{firstLine: row.Line}
+			groups[row.DEOP] = g
+			groupOrder = append(groupOrder, row.DEOP)
+		}
+		if row.PortType == "P" {
+			g.p++
+		} else {
+			g.r++
+		}
+	}
+
+	// DE/OP 단위로 제공자(P)와 수신자(R) 구성을 검사합니다.
+	for _, deOp := range groupOrder {
+		g := groups[deOp]
+		switch {
+		case g.p == 0:
+			rep.add(name, g.firstLine, SeverityWarning, "DE/OP %s에 제공자(P)가 없어 의존관계가 만들어지지 않습니다", deOp)
+		case g.r == 0:
+			rep.add(name, g.firstLine, SeverityWarning, "DE/OP %s에 수신자(R)가 없어 의존관계가 만들어지지 않습니다", deOp)
+		case g.p > 1 && g.r > 1:
+			rep.add(name, g.firstLine, SeverityWarning, "DE/OP %s는 P %d개, R %d개의 N:M 구성입니다(%s)", deOp, g.p, g.r, nmPolicyText(nmPolicy))
+		}
+	}
+}
+
+// N:M 그룹이 정책에 따라 어떻게 처리되는지 설명합니다.
+func nmPolicyText(policy string) string {
+	switch policy {
+	case Public_data.NMPolicyPairByName:
+		return "Port/Interface 이름이 같은 P와 R만 연결됩니다"
+	case Public_data.NMPolicySkip:
+		return "의존관계 분석에서 제외됩니다"
+	default:
+		return "모든 P × R 조합으로 연결됩니다"
+	}
+}
+
+// ======================== rq_versus_component.csv ========================
+// 열: [요구사항 ID], 컴포넌트 (헤더 없음)
+// 반환값: 요구사항 ID 집합(complexity.json 교차 검사에 사용)
+func validateRqVersusComponent(rep *Report, path string, components map[string]bool) map[string]bool {
+	name := fileLabel(path, "rq_versus_component.csv")
+	rows, lines, ok := readCSV(rep, path, name)
+	if !ok {
+		return nil
+	}
+	if len(rows) == 0 {
+		rep.add(name, 0, SeverityWarning, "파일이 비어 있어 M2 결과가 없습니다")
+		return nil
+	}
+
+	reqs := make(map[string]bool)
+	firstLine := make(map[string]int)
+	for i, row := range rows {
+		line := lines[i]
+		if isBlankRow(row) {
+			continue
+		}
+		if len(row) < 2 {
+			rep.add(name, line, SeverityWarning, "열이 %d개뿐이라 M2에서 제외됩니다(요구사항, 컴포넌트 필요)", len(row))
+			continue
+		}
+		req := strings.TrimSpace(row[0])
+		comp := strings.TrimSpace(row[1])
+		if !reqIDPattern.MatchString(req) {
+			rep.add(name, line, SeverityWarning, "요구사항 %q는 [ID] 형식이 아니어서 complexity.json과 매칭되지 않습니다", req)
+		}
+		if comp == "" {
+			rep.add(name, line, SeverityWarning, "요구사항 %s의 컴포넌트가 비어 있습니다", req)
+		} else if components != nil && !components[strings.ReplaceAll(comp, ".", "")] {
+			rep.add(name, line, SeverityWarning, "요구사항 %s의 컴포넌트 %s가 component_info.csv에 없습니다", req, comp)
+		}
+		if prev, dup := firstLine[req]; dup {
+			rep.add(name, line, SeverityWarning, "요구사항 %s가 중복되었습니다(처음: %d행). 마지막 행의 컴포넌트가 사용됩니다", req, prev)
+		} else {
+			firstLine[req] = line
+		}
+		reqs[req] = true
+	}
+	return reqs
+}
+
+var reqIDPattern = regexp.MustCompile(`^\[[^\]]+\]$`)
+var reqPrefixPattern = regexp.MustCompile(`^\[[^\]]+\]`)
+
+// ======================== complexity.json ========================
+// 형식: { "[요구사항 ID] 설명": 숫자, ... }
+func validateComplexity(rep *Report, path string, reqs map[string]bool) {
+	name := fileLabel(path, "complexity.json")
+	if strings.TrimSpace(path) == "" {
+		rep.add(name, 0, SeverityError, "경로가 설정되지 않았습니다")
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		rep.add(name, 0, SeverityError, "파일을 읽을 수 없습니다: %v", err)
+		return
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		line := 0
+		if se, ok := err.(*json.SyntaxError); ok {
+			line = lineOfOffset(data, se.Offset)
+		}
+		rep.add(name, line, SeverityError, "JSON 형식 오류(키: 문자열, 값: 숫자인 객체여야 합니다): %v", err)
+		return
+	}
+
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		line := lineOfKey(data, k)
+		var v float64
+		if err := json.Unmarshal(raw[k], &v); err != nil {
+			rep.add(name, line, SeverityError, "%q의 값 %s가 숫자가 아닙니다", k, string(raw[k]))
+		}
+		prefix := reqPrefixPattern.FindString(k)
+		if prefix == "" {
+			rep.add(name, line, SeverityWarning, "%q는 [ID]로 시작하지 않아 M2에서 제외됩니다", k)
+			continue
+		}
+		if reqs != nil && !reqs[prefix] {
+			rep.add(name, line, SeverityWarning, "%s가 rq_versus_component.csv에 없어 M2에서 제외됩니다", prefix)
+		}
+	}
+}
+
+// ======================== 공통 유틸 ========================
+
+func fileLabel(path, fallback string) string {
+	if strings.TrimSpace(path) == "" {
+		return fallback
+	}
+	return filepath.Base(path)
+}
+
+func isBlankRow(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// JSON 바이트 오프셋을 1부터 시작하는 행 번호로 변환합니다.
+func lineOfOffset(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// 키가 처음 나오는 행 번호를 찾습니다. 찾지 못하면 0을 반환합니다.
+func lineOfKey(data []byte, key string) int {
+	quoted, err := json.Marshal(key)
+	if err != nil {
+		return 0
+	}
+	i := bytes.Index(data, quoted)
+	if i < 0 {
+		return 0
+	}
+	return lineOfOffset(data, int64(i))
+}
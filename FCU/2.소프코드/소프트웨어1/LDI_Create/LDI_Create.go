@@ -1,43 +1,52 @@
 package LDI_Create
 
 import (
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/Public_data"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
 )
 
+// GenerateLDIXml는 의존관계와 강도를 기반으로 LDI 문서를 메모리에 생성하여 반환합니다.
+// 파일 기록은 모든 지표 병합이 끝난 뒤 SaveMainLDI에서 한 번만 수행합니다.
+func GenerateLDIXml(dependencies map[string][]string, strengths map[string]map[string]int) (*LDI_Model.Document, error) {
+	doc := LDI_Model.New()
 
-func GenerateLDIXml(dependencies map[string][]string, strengths map[string]map[string]int) error {
-	//출력 결과인 ldi.xml 파일의 올바른 경로를 조합(결합)합니다.
-	outputPath := filepath.Join(Public_data.OutputDir, "result.ldi.xml")
-	//outputPath에 저장된 경로에 ldi.xml 파일을 생성합니다.
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("출력 파일 생성 실패: %v", err)
+	// 실행할 때마다 같은 순서로 출력되도록 컴포넌트 이름을 정렬합니다.
+	users := make([]string, 0, len(dependencies))
+	for user := range dependencies {
+		users = append(users, user)
 	}
-	defer file.Close()
-	
-	//ldi.xml의 고정 형식
-	_, _ = file.WriteString("<ldi>\n")
+	sort.Strings(users)
 
 	//두 개의 map을 기반으로 내용을 작성(기록)합니다.
-	for user, providers := range dependencies {
-		_, _ = file.WriteString(fmt.Sprintf("  <element name=\"%s\">\n", user))
-		for _, provider := range providers {
+	for _, user := range users {
+		el := doc.AddElement(user)
+		for _, provider := range dependencies[user] {
 			if strengthVal, ok := strengths[user][provider]; ok {
-    			// 항상 strength를 쓰다.
-    			_, _ = file.WriteString(fmt.Sprintf("    <uses provider=\"%s\" strength=\"%d\"/>\n", provider, strengthVal))
+				// 항상 strength를 쓰다.
+				el.AddUses(provider, strengthVal)
 			} else {
-    			// 강도를 찾지 못하면 기본값으로 1을 작성합니다.
-    			_, _ = file.WriteString(fmt.Sprintf("    <uses provider=\"%s\" strength=\"1\"/>\n", provider))
+				// 강도를 찾지 못하면 기본값으로 1을 작성합니다.
+				el.AddUses(provider, 1)
 			}
 		}
-		_, _ = file.WriteString("  </element>\n")
 	}
-	//ldi.xml의 고정 형식
-	_, _ = file.WriteString("</ldi>\n")
+
+	return doc, nil
+}
+
+// SaveMainLDI는 모든 지표가 병합된 주 LDI를 cfg.OutputDir/result.ldi.xml에 기록합니다.
+func SaveMainLDI(cfg *Public_data.RunConfig, mainLDI *LDI_Model.Document) error {
+	if mainLDI == nil {
+		return fmt.Errorf("주 LDI가 생성되지 않았습니다. 먼저 의존 관계 분석을 수행하세요.")
+	}
+	//출력 결과인 ldi.xml 파일의 올바른 경로를 조합(결합)합니다.
+	outputPath := cfg.ResultPath()
+	if err := mainLDI.Save(outputPath); err != nil {
+		return fmt.Errorf("출력 파일 생성 실패: %v", err)
+	}
 
 	fmt.Println("LDI 파일이 기록됨：", outputPath)
 	return nil
-}
\ No newline at end of file
+}
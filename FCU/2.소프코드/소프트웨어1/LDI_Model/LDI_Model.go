@@ -0,0 +1,217 @@
+// LDI_Model.go
+package LDI_Model
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// <property name="...">값</property>
+type Property struct {
+	XMLName xml.Name `xml:"property"`
+	Name    string   `xml:"name,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+// <uses provider="..." strength="..."/>
+type Uses struct {
+	XMLName  xml.Name `xml:"uses"`
+	Provider string   `xml:"provider,attr"`
+	Strength string   `xml:"strength,attr,omitempty"`
+}
+
+// <element name="..."> ... </element>
+type Element struct {
+	XMLName  xml.Name   `xml:"element"`
+	Name     string     `xml:"name,attr"`
+	Uses     []Uses     `xml:"uses"`
+	Property []Property `xml:"property"`
+}
+
+// Document는 하나의 ldi.xml 전체(<ldi> 루트)를 메모리에 보관합니다.
+// 모든 단계(SWC 의존관계, M1~M6)는 이 모델을 공유하며, 파일 읽기/쓰기는 Load/Save로만 수행합니다.
+type Document struct {
+	XMLName xml.Name   `xml:"ldi"`
+	Items   []*Element `xml:"element"`
+
+	index   map[string]int // element name → Items 인덱스
+	indexed int            // index를 만들 때 반영한 Items 개수(이름이 중복되어도 index가 최신인지 판단할 수 있도록 이름 수가 아닌 항목 수를 셉니다)
+}
+
+// MergeOptions는 Merge 시 조각(fragment) 문서를 주 문서에 반영하는 방식을 결정합니다.
+type MergeOptions struct {
+	CreateMissing bool // true: 주 문서에 없는 요소는 새로 추가합니다. false: 건너뜁니다.
+	Replace       bool // true: 같은 이름의 속성이 있으면 값을 덮어씁니다. false: 기존 값을 유지합니다.
+}
+
+// 빈 문서를 생성합니다.
+func New() *Document {
+	return &Document{index: make(map[string]int)}
+}
+
+// Load는 path의 ldi.xml 파일을 읽어 Document로 파싱합니다.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LDI 파일 읽기 실패 [%s]: %v", path, err)
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("LDI XML 파싱 실패 [%s]: %v", path, err)
+	}
+	return doc, nil
+}
+
+// Parse는 ldi.xml 내용을 Document로 변환합니다.
+func Parse(data []byte) (*Document, error) {
+	doc := New()
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	doc.reindex()
+	return doc, nil
+}
+
+// Marshal은 문서를 XML 헤더를 포함한 바이트열로 직렬화합니다.
+func (d *Document) Marshal() ([]byte, error) {
+	out, err := xml.MarshalIndent(d, "  ", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// Save는 문서를 path에 덮어씁니다.
+func (d *Document) Save(path string) error {
+	data, err := d.Marshal()
+	if err != nil {
+		return fmt.Errorf("LDI XML 직렬화 실패: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("LDI 파일 쓰기 실패 [%s]: %v", path, err)
+	}
+	return nil
+}
+
+// Element는 이름으로 요소를 찾습니다. 없으면 nil을 반환합니다.
+func (d *Document) Element(name string) *Element {
+	if d.index == nil || d.indexed != len(d.Items) {
+		d.reindex()
+	}
+	if i, ok := d.index[name]; ok {
+		return d.Items[i]
+	}
+	return nil
+}
+
+// AddElement는 이름에 해당하는 요소를 반환하며, 없으면 새로 추가합니다.
+func (d *Document) AddElement(name string) *Element {
+	if el := d.Element(name); el != nil {
+		return el
+	}
+	el := &Element{Name: name}
+	d.Items = append(d.Items, el)
+	d.index[name] = len(d.Items) - 1
+	d.indexed = len(d.Items)
+	return el
+}
+
+// Merge는 조각 문서 frag의 속성과 uses를 주 문서 d에 병합합니다.
+//   - 속성: Replace가 false이면 아직 없는 속성만 추가하고, true이면 덮어씁니다.
+//   - uses: 같은 provider가 있으면 strength를 누적하고, 없으면 새로 추가합니다.
+func (d *Document) Merge(frag *Document, opts MergeOptions) {
+	if frag == nil {
+		return
+	}
+	for _, src := range frag.Items {
+		dst := d.Element(src.Name)
+		if dst == nil {
+			if !opts.CreateMissing {
+				continue
+			}
+			dst = d.AddElement(src.Name)
+		}
+		for _, p := range src.Property {
+			if opts.Replace {
+				dst.SetProperty(p.Name, p.Value)
+			} else {
+				dst.AddProperty(p.Name, p.Value)
+			}
+		}
+		for _, u := range src.Uses {
+			dst.AddUses(u.Provider, ParseStrength(u.Strength))
+		}
+	}
+}
+
+// 인덱스를 Items 기준으로 다시 구축합니다(같은 이름이 여러 개면 첫 번째를 사용).
+func (d *Document) reindex() {
+	d.index = make(map[string]int, len(d.Items))
+	for i, el := range d.Items {
+		if _, ok := d.index[el.Name]; !ok {
+			d.index[el.Name] = i
+		}
+	}
+	d.indexed = len(d.Items)
+}
+
+// PropertyValue는 이름에 해당하는 속성 값을 반환합니다.
+func (e *Element) PropertyValue(name string) (string, bool) {
+	for _, p := range e.Property {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// AddProperty는 같은 이름의 속성이 없을 때만 추가하며, 추가 여부를 반환합니다.
+func (e *Element) AddProperty(name, value string) bool {
+	if _, ok := e.PropertyValue(name); ok {
+		return false
+	}
+	e.Property = append(e.Property, Property{Name: name, Value: value})
+	return true
+}
+
+// SetProperty는 같은 이름의 속성이 있으면 값을 바꾸고, 없으면 추가합니다.
+func (e *Element) SetProperty(name, value string) {
+	for i := range e.Property {
+		if e.Property[i].Name == name {
+			e.Property[i].Value = value
+			return
+		}
+	}
+	e.Property = append(e.Property, Property{Name: name, Value: value})
+}
+
+// AddUses는 provider에 대한 의존을 추가합니다. 이미 있으면 strength를 누적합니다.
+func (e *Element) AddUses(provider string, strength int) {
+	provider = strings.TrimSpace(provider)
+	if provider == "" || strength <= 0 {
+		return
+	}
+	for i := range e.Uses {
+		if strings.TrimSpace(e.Uses[i].Provider) == provider {
+			e.Uses[i].Strength = strconv.Itoa(ParseStrength(e.Uses[i].Strength) + strength)
+			return
+		}
+	}
+	e.Uses = append(e.Uses, Uses{Provider: provider, Strength: strconv.Itoa(strength)})
+}
+
+// ParseStrength는 strength 문자열을 정수로 변환합니다. 비어 있거나 잘못된 값, 음수는 0으로 처리합니다.
+func ParseStrength(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
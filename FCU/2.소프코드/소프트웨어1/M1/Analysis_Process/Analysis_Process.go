@@ -1,92 +1,247 @@
 package Analysis_Process
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Lint"
+	"FCU_Tools/M1/Model_Reader"
 	"FCU_Tools/M1/System_Analysis"
+	"FCU_Tools/Public_data"
 )
 
-// 1단계는 고정되어 있으며, BuildDir/<Model>/simulink/systems/system_root.xml만 분석합니다.
-func RunAnalysis(maxDepth int) {
-
-	buildRoot := M1_Public_Data.BuildDir
-	if buildRoot == "" {
-		fmt.Println("❌ BuildDir이 비어 있습니다. 먼저 SetWorkDir()를 호출하여 작업 공간을 초기화하세요.")
-		return
+// 1단계는 고정되어 있으며, 각 모델의 simulink/systems/system_root.xml만 분석합니다.
+// models는 File_Utils_M1.OpenModels로 연 모델이며, slx를 메모리에서 읽는지 디스크에 풀어 읽는지는 상관하지 않습니다.
+// resolver는 라이브러리 링크와 참조 모델을 모델 디렉터리에서 찾는 데 사용합니다(nil이면 말단 블록으로 처리).
+// rules는 레벨별 노드 선택 규칙이고, maxDepth가 Public_data.M1DepthUnlimited(0)이면 모델의 가장 깊은 레벨까지 분석합니다.
+//
+// workers개의 워커가 모델을 병렬로 분석합니다(1 이하이면 순차 분석). 모델마다 별도의 ModelResult에 결과(블록 트리)를 모은 뒤,
+// 완료 순서와 관계없이 models 순서대로 콘솔 메시지를 출력하고 결과를 반환합니다.
+// 한 모델의 분석이 실패해도 나머지 모델은 계속 분석하며, 실패한 모델의 오류를 모아서 반환합니다(실패한 모델도 실패 전까지의 결과를 포함합니다).
+func RunAnalysis(models []Model_Reader.Model, resolver *Model_Reader.Resolver, rules Public_data.M1LevelRules, maxDepth, workers int) ([]*M1_Public_Data.ModelResult, error) {
+	if workers < 1 {
+		workers = 1
 	}
 
-	// BuildDir 하위의 모델 디렉터리
-	modelDirs, err := os.ReadDir(buildRoot)
-	if err != nil {
-		fmt.Println("❌ BuildDir 디렉터리를 읽을 수 없습니다：", err)
-		return
+	results := make([]*M1_Public_Data.ModelResult, len(models))
+	done := make([]chan struct{}, len(models))
+	for i := range done {
+		done[i] = make(chan struct{})
 	}
 
-	for _, modelEntry := range modelDirs {
-		if !modelEntry.IsDir() {
-			continue
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				results[i] = analyzeModel(models[i], resolver, rules, maxDepth)
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range models {
+			jobs <- i
 		}
+		close(jobs)
+	}()
 
-		modelName := modelEntry.Name()
-		modelPath := filepath.Join(buildRoot, modelName)
+	var analyzed []*M1_Public_Data.ModelResult
+	var errs []error
 
-		// 고정된 구조: <BuildDir>/<Model>/simulink/systems/system_root.xml
-		sysDir := filepath.Join(modelPath, "simulink", "systems")
-		xmlPath := filepath.Join(sysDir, "system_root.xml")
-
-		if _, err := os.Stat(xmlPath); err != nil {
+	// 모델 순서대로 결과를 기다려 출력하므로, 병렬 실행이어도 출력 순서가 항상 같습니다.
+	for i := range models {
+		<-done[i]
+		res := results[i]
+		if res == nil {
 			continue // 모델에 system_root.xml이 없으면 건너뜁니다.
 		}
 
-		fmt.Printf("🔍 모델 분석 [%s] (최대 깊이: %d)\n", modelName, maxDepth)
-
-		// 재귀 분석을 시작하며, 1층(L1)부터 수행합니다. L1에는 부모 노드가 없습니다.
-		err = analyzeRecursive(sysDir, "system_root.xml", 1, maxDepth, "")
-		if err != nil {
-			fmt.Println("❌ 분석 실패：", err)
-			continue
+		fmt.Print(res.Log.String())
+		analyzed = append(analyzed, res)
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("모델 분석 실패 [%s]: %v", res.Name, res.Err))
 		}
 	}
 
-	fmt.Printf("✅ 분석 완료 (최대 깊이: %d)\n", maxDepth)
+	fmt.Printf("✅ 분석 완료 (최대 깊이: %s)\n", depthText(maxDepth))
+	return analyzed, errors.Join(errs...)
+}
+
+// analyzeModel은 모델 하나를 분석하여 결과를 반환합니다. system_root.xml이 없는 모델은 nil을 반환합니다.
+func analyzeModel(model Model_Reader.Model, resolver *Model_Reader.Resolver, rules Public_data.M1LevelRules, maxDepth int) *M1_Public_Data.ModelResult {
+	// 고정된 구조: <Model>.slx 내부의 simulink/systems/system_root.xml(.mdl은 OpenMdl이 같은 구조로 변환)
+	if !model.Exists(Model_Reader.SystemPath(Model_Reader.RootSystemFile)) {
+		return nil
+	}
+
+	res := &M1_Public_Data.ModelResult{Name: model.Name()}
+	res.Logf("🔍 모델 분석 [%s] (최대 깊이: %s)\n", res.Name, depthText(maxDepth))
+
+	a := &analyzer{res: res, resolver: resolver, rules: rules, maxDepth: maxDepth}
+
+	// 재귀 분석을 시작하며, 1층(L1)부터 수행합니다. L1에는 부모 노드가 없습니다.
+	root := []string{systemKey(model, Model_Reader.RootSystemFile)}
+	if err := a.analyzeRecursive(model, Model_Reader.RootSystemFile, 1, "", "", root); err != nil {
+		res.Logf("❌ 분석 실패：%v\n", err)
+		res.Err = err
+	}
+	return res
+}
+
+// depthText는 로그에 표시할 최대 깊이입니다.
+func depthText(maxDepth int) string {
+	if maxDepth == Public_data.M1DepthUnlimited {
+		return "무제한"
+	}
+	return fmt.Sprintf("%d", maxDepth)
+}
+
+// analyzer는 모델 하나를 재귀 분석하는 동안 바뀌지 않는 값을 담습니다.
+// resolver가 nil이면 라이브러리 링크와 참조 모델을 따라가지 않고 말단 블록으로 처리합니다.
+type analyzer struct {
+	res      *M1_Public_Data.ModelResult
+	resolver *Model_Reader.Resolver
+	rules    Public_data.M1LevelRules
+	maxDepth int
 }
 
-// 재귀 분석 함수로, maxDepth에 따라 재귀 깊이를 제어합니다.
+// 재귀 분석 함수로, maxDepth에 따라 재귀 깊이를 제어합니다(M1DepthUnlimited이면 하위 system 파일이 없을 때까지 재귀합니다).
+// model은 분석할 모델(라이브러리 링크/참조 모델을 따라가면 해당 모델), file은 분석할 파일(system_xxx.xml), currentLevel은 현재 분석 레벨입니다.
 // fatherName: 현재 레벨의 System에 해당하는 ‘부모 노드 이름’이며, 다음 레벨에서 FatherNode 정보를 출력할 때 사용합니다.
-// dir는 분석 경로, file은 분석할 파일, currentLevel은 현재 분석 레벨, maxDepth는 분석할 최대 레벨(깊이)입니다.
-// fatherName은 상위(부모) 분석 대상의 이름을 의미하며, 예를 들어 system4.ldi.xml과 같이 상위 파일명을 전달합니다.
-func analyzeRecursive(dir, file string, currentLevel, maxDepth int, fatherName string) error {
+// fatherPath는 부모 노드의 SID 경로(system_root → SID → SID)이며, 이름이 중복되어도 노드를 구분할 수 있도록 사용합니다.
+// chain은 지금까지 지나온 "모델/파일" 목록이며, 순환 참조를 막는 데 사용합니다.
+func (a *analyzer) analyzeRecursive(model Model_Reader.Model, file string, currentLevel int, fatherName, fatherPath string, chain []string) error {
+	unlimited := a.maxDepth == Public_data.M1DepthUnlimited
+
 	// 현재 레벨이 최대 깊이를 초과하면 재귀를 중단합니다.
-	if currentLevel > maxDepth {
+	if !unlimited && currentLevel > a.maxDepth {
 		return nil
 	}
 
-	// 통합 진입점으로, System_Analysis가 level에 따라 필터링 로직을 결정합니다.
-	subsystems, err := System_Analysis.AnalyzeSubSystemsInFile(dir, file, currentLevel, fatherName)
+	// 통합 진입점으로, 현재 레벨의 규칙에 따라 System_Analysis가 노드로 셀 블록을 결정합니다.
+	subsystems, err := System_Analysis.AnalyzeSubSystemsInFile(a.res, model, file, currentLevel, a.rules.ForLevel(currentLevel), fatherName, fatherPath)
 	if err != nil {
 		return err
 	}
 
+	// 같은 system 파일에서 연결 결함(연결되지 않은 포트, Ground/Terminator, 끝이 없는 선)을 찾아 부모 노드별로 기록합니다.
+	// 점검에 실패해도 분석은 계속합니다.
+	findings, err := Model_Lint.LintFile(model, file)
+	if err != nil {
+		a.res.Logf("⚠️ 모델 점검 실패 [%s]: %v\n", file, err)
+	} else {
+		a.res.Lint = append(a.res.Lint, M1_Public_Data.LintSystem{
+			File:       systemKey(model, file),
+			Level:      currentLevel,
+			Father:     fatherName,
+			FatherPath: fatherPath,
+			Findings:   findings,
+		})
+	}
+
 	// 다음 레벨을 재귀적으로 분석합니다.
-	if len(subsystems) > 0 && currentLevel < maxDepth {
+	if len(subsystems) > 0 && (unlimited || currentLevel < a.maxDepth) {
 		nextLevel := currentLevel + 1
 		for _, sub := range subsystems {
-			nextFile := fmt.Sprintf("system_%s.xml", sub.SID)
-			nextFull := filepath.Join(dir, nextFile)
-
-			if _, err := os.Stat(nextFull); err == nil {
-				// 다음 레벨의 부모 노드 = 현재 레벨의 서브시스템 이름
-				nextFather := strings.TrimSpace(sub.Name)
-				if err := analyzeRecursive(dir, nextFile, nextLevel, maxDepth, nextFather); err != nil {
-					return err
-				}
+			nextModel, nextFile, ok := a.childSystem(model, sub)
+			if !ok {
+				continue
+			}
+
+			// 잘못된 모델이나 서로를 참조하는 라이브러리/모델이 있어도 무한히 재귀하지 않도록, 이미 지나온 파일은 건너뜁니다.
+			key := systemKey(nextModel, nextFile)
+			if contains(chain, key) {
+				a.res.Logf("⚠️ 순환 참조로 건너뜀 [%s]: %s\n", a.res.Name, key)
+				continue
+			}
+
+			// 다음 레벨의 부모 노드 = 현재 레벨의 서브시스템 이름
+			nextFather := strings.TrimSpace(sub.Name)
+			nextFatherPath := M1_Public_Data.JoinSIDPath(fatherPath, sub.SID)
+			nextChain := append(append([]string(nil), chain...), key)
+			if err := a.analyzeRecursive(nextModel, nextFile, nextLevel, nextFather, nextFatherPath, nextChain); err != nil {
+				return err
 			}
 		}
 	}
 
 	return nil
 }
+
+// childSystem은 블록의 하위 내용이 들어 있는 모델과 system 파일을 반환합니다. 하위 내용이 없으면 ok=false입니다.
+//   - 일반 블록: 같은 모델의 system_<SID>.xml
+//   - 라이브러리 링크(Reference): 모델 디렉터리의 라이브러리에서 SourceBlock이 가리키는 블록의 system 파일
+//   - 모델 참조(ModelReference): 모델 디렉터리의 참조 모델의 system_root.xml
+//
+// 라이브러리나 참조 모델을 찾지 못하거나 보호된 모델(.slxp)이면 로그를 남기고 말단 블록으로 처리합니다.
+func (a *analyzer) childSystem(model Model_Reader.Model, sub System_Analysis.SubSystemInfo) (Model_Reader.Model, string, bool) {
+	switch {
+	case sub.ModelReference != "":
+		target, ok := a.openReference(sub.Name, sub.ModelReference)
+		if !ok {
+			return nil, "", false
+		}
+		a.res.Logf("🔗 모델 참조 [%s] → %s\n", sub.Name, sub.ModelReference)
+		return target, Model_Reader.RootSystemFile, true
+
+	case sub.LibraryLink != "":
+		libName, blockPath := System_Analysis.SplitSourceBlock(sub.LibraryLink)
+		lib, ok := a.openReference(sub.Name, libName)
+		if !ok {
+			return nil, "", false
+		}
+		file, err := System_Analysis.ResolveLibraryBlock(lib, blockPath)
+		if err != nil {
+			a.res.Logf("⚠️ 라이브러리 링크를 따라갈 수 없습니다 [%s]: %v\n", sub.Name, err)
+			return nil, "", false
+		}
+		if !lib.Exists(Model_Reader.SystemPath(file)) {
+			return nil, "", false // 하위 내용이 없는 라이브러리 블록
+		}
+		a.res.Logf("🔗 라이브러리 링크 [%s] → %s\n", sub.Name, sub.LibraryLink)
+		return lib, file, true
+	}
+
+	file := Model_Reader.SystemFile(sub.SID)
+	if !model.Exists(Model_Reader.SystemPath(file)) {
+		return nil, "", false
+	}
+	return model, file, true
+}
+
+// openReference는 라이브러리 또는 참조 모델을 모델 디렉터리에서 찾아 엽니다.
+func (a *analyzer) openReference(blockName, name string) (Model_Reader.Model, bool) {
+	if a.resolver == nil {
+		return nil, false
+	}
+	target, err := a.resolver.Open(name)
+	if err != nil {
+		a.res.Logf("ℹ️ 참조 대상을 찾을 수 없어 말단 블록으로 처리합니다 [%s]: %v\n", blockName, err)
+		return nil, false
+	}
+	if !target.Exists(Model_Reader.SystemPath(Model_Reader.RootSystemFile)) {
+		if p, _ := a.resolver.Path(name); Model_Reader.IsProtected(p) {
+			a.res.Logf("⚠️ 보호된 모델(.slxp)은 내부를 분석할 수 없어 말단 블록으로 처리합니다 [%s]: %s\n", blockName, p)
+		} else {
+			a.res.Logf("⚠️ 참조 모델에 system_root.xml이 없어 말단 블록으로 처리합니다 [%s]: %s\n", blockName, target.Name())
+		}
+		return nil, false
+	}
+	return target, true
+}
+
+// systemKey는 순환 참조 검사에 사용하는 "모델/파일" 키입니다.
+func systemKey(model Model_Reader.Model, file string) string {
+	return model.Name() + "/" + file
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,23 +1,15 @@
 package C_S_Analysis
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Reader"
 )
 
-// 외부에 노출되는 C-S 포트 정보
-type CSPort struct {
-	Name      string // 포트명(P Name="Name"에서 추출)
-	BlockType string // Inport / Outport（Require → Inport, Provide → Outport）
-	SID       string // 여기서는 값을 고정으로 "unknow"로 설정합니다.
-	PortType  string // 값을 "C-S"로 고정합니다.
-}
-
 // 내부 XML 구조
 type xmlP struct {
 	Name  string `xml:"Name,attr"`
@@ -37,69 +29,324 @@ type xmlGraphicalInterface struct {
 	Provides []xmlProvideFunction `xml:"ProvideFunction"`
 }
 
-// BuildDir<modelName>\simulink\graphicalInterface.xml에서 C-S 포트를 파싱합니다.
-func GetCSPorts(modelName string) ([]CSPort, error) {
-	var result []CSPort
+// system_xxx.xml의 블록(C-S 구현 블록을 찾는 데 필요한 값만)
+type xmlBlock struct {
+	BlockType string `xml:"BlockType,attr"`
+	Name      string `xml:"Name,attr"`
+	SID       string `xml:"SID,attr"`
+	Ps        []xmlP `xml:"P"`
+}
+
+type xmlSystem struct {
+	Blocks []xmlBlock `xml:"Block"`
+}
+
+func param(ps []xmlP, name string) string {
+	for _, p := range ps {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
+// 모델의 simulink/graphicalInterface.xml(없으면 graphicalInterface.json)에서 C-S 포트를 파싱하고,
+// 각 포트를 구현한 블록(Require: Function Caller, Provide: Simulink Function)을 system_xxx.xml에서 찾아
+// SID, SID 경로, 포함하는 SubSystem과 연산의 입출력 인자(FunctionPrototype)를 채웁니다.
+// 구현 블록을 찾지 못한 포트는 Blocks가 비어 있으며, 블록의 포트로 변환하면 SID가 "unknow"입니다.
+// source는 실제로 읽은 파일(slx 내부 경로)입니다.
+func GetCSPorts(model Model_Reader.Model) (ports []M1_Public_Data.CSPort, source string, err error) {
+	var result []M1_Public_Data.CSPort
+
+	if model == nil {
+		return result, "", nil
+	}
+
+	requires, provides, source, err := readGraphicalInterface(model)
+	if err != nil {
+		return result, source, err
+	}
+
+	// 구현 블록은 system_root.xml부터 SubSystem을 따라 내려가며 찾습니다. 찾지 못해도 포트 목록은 반환합니다.
+	impl := newImplIndex()
+	scanErr := impl.scan(model, Model_Reader.RootSystemFile, "", "", make(map[string]bool))
+
+	// RequireFunction → Inport로 간주합니다.
+	for _, name := range requires {
+		result = append(result, impl.port(name, "Inport", impl.callers[name]))
+	}
+
+	// ProvideFunction → Outport로 간주합니다.
+	for _, name := range provides {
+		result = append(result, impl.port(name, "Outport", impl.providers[name]))
+	}
+
+	if scanErr != nil {
+		return result, source, fmt.Errorf("C-S 구현 블록 검색 실패: %w", scanErr)
+	}
+	return result, source, nil
+}
 
-	if M1_Public_Data.BuildDir == "" || modelName == "" {
-		return result, nil
+// readGraphicalInterface는 graphicalInterface.xml을, 없으면 graphicalInterface.json을 읽어
+// RequireFunction/ProvideFunction의 이름 목록과 읽은 파일을 반환합니다.
+func readGraphicalInterface(model Model_Reader.Model) (requires, provides []string, source string, err error) {
+	if !model.Exists(Model_Reader.GraphicalInterfaceFile) && model.Exists(Model_Reader.GraphicalInterfaceJSONFile) {
+		source = Model_Reader.GraphicalInterfaceJSONFile
+		requires, provides, err = readGraphicalInterfaceJSON(model)
+		return requires, provides, source, err
 	}
 
-	// BuildDir\<Model>\simulink\graphicalInterface.xml
-	giPath := filepath.Join(M1_Public_Data.BuildDir, modelName, "simulink", "graphicalInterface.xml")
+	// <Model>.slx 내부의 simulink/graphicalInterface.xml
+	source = Model_Reader.GraphicalInterfaceFile
+	giPath := model.Location(source)
 
-	data, err := os.ReadFile(giPath)
+	data, err := model.ReadFile(source)
 	if err != nil {
 		// 파일이 없거나 읽기에 실패하면, 오류 정보를 포함하되 빈 리스트를 반환하며 경고 출력 여부는 호출 측에서 결정합니다.
-		return result, fmt.Errorf("graphicalInterface.xml 읽기 실패 [%s]: %w", giPath, err)
+		return nil, nil, source, fmt.Errorf("graphicalInterface.xml/json 읽기 실패 [%s]: %w", giPath, err)
 	}
 
 	var gi xmlGraphicalInterface
 	if err := xml.Unmarshal(data, &gi); err != nil {
-		return result, fmt.Errorf("graphicalInterface.xml 파싱 실패 [%s]: %w", giPath, err)
+		return nil, nil, source, fmt.Errorf("graphicalInterface.xml 파싱 실패 [%s]: %w", giPath, err)
 	}
-
-	// RequireFunction → Inport로 간주합니다.
 	for _, rf := range gi.Requires {
-		name := ""
-		for _, p := range rf.Ps {
-			if p.Name == "Name" {
-				name = normalizeName(p.Value)
-				break
-			}
+		if name := normalizeName(param(rf.Ps, "Name")); name != "" {
+			requires = append(requires, name)
 		}
-		if name == "" {
-			continue
+	}
+	for _, pf := range gi.Provides {
+		if name := normalizeName(param(pf.Ps, "Name")); name != "" {
+			provides = append(provides, name)
 		}
-		result = append(result, CSPort{
-			Name:      name,
-			BlockType: "Inport",
-			SID:       "unknow",
-			PortType:  "C-S",
-		})
 	}
+	return requires, provides, source, nil
+}
 
-	// ProvideFunction → Outport로 간주합니다.
-	for _, pf := range gi.Provides {
-		name := ""
-		for _, p := range pf.Ps {
-			if p.Name == "Name" {
-				name = normalizeName(p.Value)
-				break
+// graphicalInterface.json은 XML과 같은 항목을 키로 가지며, 여러 개인 항목은 배열로 저장됩니다.
+//
+//	{ "GraphicalInterface": { "RootInports": [ { "Name": "In1" } ], "RequireFunctions": [ { "Name": "f" } ], ... } }
+//
+// 릴리스에 따라 키가 단수/복수이거나 값이 객체 하나일 수 있으므로 모두 받아들입니다.
+var (
+	jsonRequireKeys = []string{"RequireFunctions", "RequireFunction", "RequiredFunctions"}
+	jsonProvideKeys = []string{"ProvideFunctions", "ProvideFunction", "ProvidedFunctions"}
+)
+
+func readGraphicalInterfaceJSON(model Model_Reader.Model) (requires, provides []string, err error) {
+	giPath := model.Location(Model_Reader.GraphicalInterfaceJSONFile)
+
+	data, err := model.ReadFile(Model_Reader.GraphicalInterfaceJSONFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("graphicalInterface.json 읽기 실패 [%s]: %w", giPath, err)
+	}
+
+	var doc struct {
+		GraphicalInterface map[string]json.RawMessage `json:"GraphicalInterface"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("graphicalInterface.json 파싱 실패 [%s]: %w", giPath, err)
+	}
+
+	names := func(keys []string) ([]string, error) {
+		var list []string
+		for _, key := range keys {
+			raw, ok := doc.GraphicalInterface[key]
+			if !ok {
+				continue
+			}
+			got, err := jsonFunctionNames(raw)
+			if err != nil {
+				return nil, fmt.Errorf("graphicalInterface.json 파싱 실패 [%s] %s: %w", giPath, key, err)
 			}
+			list = append(list, got...)
 		}
-		if name == "" {
-			continue
+		return list, nil
+	}
+	if requires, err = names(jsonRequireKeys); err != nil {
+		return nil, nil, err
+	}
+	if provides, err = names(jsonProvideKeys); err != nil {
+		return nil, nil, err
+	}
+	return requires, provides, nil
+}
+
+// jsonFunctionNames는 함수 항목(객체 하나 또는 배열)에서 Name 값을 꺼냅니다.
+func jsonFunctionNames(raw json.RawMessage) ([]string, error) {
+	type entry struct {
+		Name string `json:"Name"`
+	}
+	var list []entry
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var one entry
+		if err2 := json.Unmarshal(raw, &one); err2 != nil {
+			return nil, err
 		}
-		result = append(result, CSPort{
-			Name:      name,
-			BlockType: "Outport",
-			SID:       "unknow",
-			PortType:  "C-S",
-		})
+		list = []entry{one}
 	}
 
-	return result, nil
+	var names []string
+	for _, e := range list {
+		if name := normalizeName(e.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// 함수 이름 → 구현 블록
+type implIndex struct {
+	callers    map[string][]M1_Public_Data.CSBlock
+	providers  map[string][]M1_Public_Data.CSBlock
+	prototypes map[string]string // 함수 이름 → 처음 찾은 FunctionPrototype
+	names      map[string]string // SubSystem의 SID 경로 → 이름
+}
+
+func newImplIndex() *implIndex {
+	return &implIndex{
+		callers:    make(map[string][]M1_Public_Data.CSBlock),
+		providers:  make(map[string][]M1_Public_Data.CSBlock),
+		prototypes: make(map[string]string),
+		names:      make(map[string]string),
+	}
+}
+
+// ownerOf는 SID 경로에 해당하는 SubSystem 이름을 반환합니다(최상위는 빈 문자열).
+func (x *implIndex) ownerOf(path string) string {
+	return x.names[path]
+}
+
+// splitLast는 SID 경로를 부모 경로와 마지막 SID로 나눕니다.
+func splitLast(path string) (string, string) {
+	i := strings.LastIndex(path, M1_Public_Data.SIDPathSeparator)
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+// scan은 file(system_xxx.xml)의 블록을 살펴 Function Caller와 Simulink Function을 기록하고 하위 SubSystem으로 내려갑니다.
+// path는 file을 가진 SubSystem의 SID 경로(최상위는 빈 문자열), owner는 그 SubSystem의 이름입니다.
+// 라이브러리 링크와 참조 모델은 다른 모델이므로 내려가지 않습니다.
+func (x *implIndex) scan(model Model_Reader.Model, file, path, owner string, visited map[string]bool) error {
+	if visited[file] {
+		return nil
+	}
+	visited[file] = true
+
+	data, err := model.ReadFile(Model_Reader.SystemPath(file))
+	if err != nil {
+		return fmt.Errorf("XML 읽기 실패 [%s]: %w", model.Location(Model_Reader.SystemPath(file)), err)
+	}
+	var sys xmlSystem
+	if err := xml.Unmarshal(data, &sys); err != nil {
+		return fmt.Errorf("XML 파싱 실패 [%s]: %w", model.Location(Model_Reader.SystemPath(file)), err)
+	}
+
+	for _, b := range sys.Blocks {
+		blockPath := M1_Public_Data.JoinSIDPath(path, b.SID)
+		switch b.BlockType {
+		case "FunctionCaller":
+			proto := param(b.Ps, "FunctionPrototype")
+			name := functionName(proto)
+			if name == "" {
+				name = normalizeName(b.Name)
+			}
+			x.add(x.callers, name, proto, M1_Public_Data.CSBlock{SID: b.SID, Path: blockPath, Subsystem: owner})
+
+		case "TriggerPort":
+			// Simulink Function은 IsSimulinkFunction=on인 TriggerPort를 가진 SubSystem입니다(구현 블록은 그 SubSystem).
+			if !strings.EqualFold(param(b.Ps, "IsSimulinkFunction"), "on") || path == "" {
+				continue
+			}
+			proto := param(b.Ps, "FunctionPrototype")
+			name := param(b.Ps, "FunctionName")
+			if name == "" {
+				name = functionName(proto)
+			}
+			if name == "" {
+				name = owner
+			}
+			parent, sid := splitLast(path)
+			x.add(x.providers, normalizeName(name), proto, M1_Public_Data.CSBlock{SID: sid, Path: path, Subsystem: x.ownerOf(parent)})
+
+		case "SubSystem":
+			child := Model_Reader.SystemFile(b.SID)
+			if !model.Exists(Model_Reader.SystemPath(child)) {
+				continue
+			}
+			x.names[blockPath] = normalizeName(b.Name)
+			if err := x.scan(model, child, blockPath, normalizeName(b.Name), visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (x *implIndex) add(m map[string][]M1_Public_Data.CSBlock, name, proto string, blk M1_Public_Data.CSBlock) {
+	if name == "" {
+		return
+	}
+	m[name] = append(m[name], blk)
+	if _, ok := x.prototypes[name]; !ok && proto != "" {
+		x.prototypes[name] = proto
+	}
+}
+
+// port는 C-S 포트 하나를 만듭니다. 입출력 인자는 구현 블록의 FunctionPrototype에서 가져옵니다.
+func (x *implIndex) port(name, blockType string, blocks []M1_Public_Data.CSBlock) M1_Public_Data.CSPort {
+	proto := x.prototypes[name]
+	outputs, args := parsePrototype(proto)
+	return M1_Public_Data.CSPort{
+		Name:      name,
+		BlockType: blockType,
+		Prototype: proto,
+		Arguments: args,
+		Outputs:   outputs,
+		Blocks:    blocks,
+	}
+}
+
+// functionName은 FunctionPrototype("y = f(u)", "[a,b] = f(x)", "f(x)")에서 함수 이름(f)을 반환합니다.
+func functionName(proto string) string {
+	_, name, _ := splitPrototype(proto)
+	return name
+}
+
+// parsePrototype은 FunctionPrototype의 출력 인자와 입력 인자 목록을 반환합니다.
+func parsePrototype(proto string) (outputs, args []string) {
+	out, _, in := splitPrototype(proto)
+	return splitArgs(out), splitArgs(in)
+}
+
+func splitPrototype(proto string) (out, name, in string) {
+	proto = strings.TrimSpace(proto)
+	if proto == "" {
+		return "", "", ""
+	}
+	if i := strings.Index(proto, "="); i >= 0 {
+		out = strings.TrimSpace(proto[:i])
+		proto = strings.TrimSpace(proto[i+1:])
+	}
+	name = proto
+	if i := strings.Index(proto, "("); i >= 0 {
+		name = strings.TrimSpace(proto[:i])
+		in = strings.TrimSuffix(strings.TrimSpace(proto[i+1:]), ")")
+	}
+	return out, name, in
+}
+
+func splitArgs(s string) []string {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "["), "]"))
+	var list []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			list = append(list, a)
+		}
+	}
+	return list
 }
 
 // 이름에 포함된 줄바꿈/불필요한 공백을 하나의 공백으로 정규화합니다.
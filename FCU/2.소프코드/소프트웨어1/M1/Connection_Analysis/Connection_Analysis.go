@@ -3,9 +3,10 @@ package Connection_Analysis
 import (
 	"encoding/xml"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+
+	"FCU_Tools/M1/Model_Reader"
 )
 
 // P 태그
@@ -14,6 +15,16 @@ type xmlP struct {
 	Value string `xml:",chardata"`
 }
 
+// property는 이름에 해당하는 P 태그의 값을 반환합니다(없으면 빈 문자열).
+func property(ps []xmlP, name string) string {
+	for _, p := range ps {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
 // Branch 태그
 type xmlBranch struct {
 	Ps       []xmlP      `xml:"P"`
@@ -26,98 +37,235 @@ type xmlLine struct {
 	Branches []xmlBranch `xml:"Branch"`
 }
 
-// Line에 포함된 System만 분석 대상으로 합니다
+// Block의 Port 태그(출력 포트의 신호 이름)
+type xmlPort struct {
+	Ps []xmlP `xml:"P"`
+}
+
+// Block 태그(여기서는 SID와 출력 포트의 신호 이름만 관심)
+type xmlBlock struct {
+	SID   string    `xml:"SID,attr"`
+	Ports []xmlPort `xml:"Port"`
+}
+
+// 분석 대상 System의 Block과 Line
 type xmlSystem struct {
-	Lines []xmlLine `xml:"Line"`
+	Blocks []xmlBlock `xml:"Block"`
+	Lines  []xmlLine  `xml:"Line"`
 }
 
-// 하나의 연결 엣지: SrcSID → DstSID
+// Endpoint는 선의 한쪽 끝(블록의 포트 하나)입니다.
+// "39#out:1" → {SID: "39", Kind: "out", Index: 1}, "202#trigger" → {SID: "202", Kind: "trigger", Index: 0}
+type Endpoint struct {
+	SID   string
+	Kind  string // "out", "in", "trigger", "enable", "ifaction" 등(표기가 없으면 빈 문자열)
+	Index int    // out:N / in:N의 포트 번호(번호가 없는 포트는 0)
+}
+
+// String은 Endpoint를 모델의 표기("39#out:1", "202#trigger")로 되돌립니다.
+func (ep Endpoint) String() string {
+	switch {
+	case ep.Kind == "":
+		return ep.SID
+	case ep.Index > 0:
+		return fmt.Sprintf("%s#%s:%d", ep.SID, ep.Kind, ep.Index)
+	}
+	return ep.SID + "#" + ep.Kind
+}
+
+// 하나의 연결 엣지: Src(출력 포트) → Dst(입력 포트)
+// 한 Line에 Branch가 있으면 Dst마다 Edge가 하나씩 만들어지며, 모두 같은 Line 이름과 신호 이름을 가집니다.
 type Edge struct {
-	SrcSID string
-	DstSID string
+	Src    Endpoint
+	Dst    Endpoint
+	Line   string // Line의 Name(이름이 없는 선이면 빈 문자열)
+	Signal string // Src 블록의 출력 포트에 지정된 신호 이름(Block/Port의 Name, 없으면 빈 문자열)
 }
 
 // 특정 system_xxx.xml의 모든 연결을 파싱하여 Edge 리스트를 반환합니다.
-func AnalyzeConnectionsInFile(dir, file string) ([]Edge, error) {
-	fullPath := filepath.Join(dir, file)
-
-	data, err := os.ReadFile(fullPath)
+func AnalyzeConnectionsInFile(model Model_Reader.Model, file string) ([]Edge, error) {
+	sys, err := readSystem(model, file)
 	if err != nil {
-		return nil, fmt.Errorf("XML 읽기 실패 [%s]: %w", fullPath, err)
+		return nil, err
 	}
 
-	var sys xmlSystem
-	if err := xml.Unmarshal(data, &sys); err != nil {
-		return nil, fmt.Errorf("XML 파싱 실패 [%s]: %w", fullPath, err)
+	// SID#out:N → 신호 이름
+	signals := make(map[string]string)
+	for _, b := range sys.Blocks {
+		for _, p := range b.Ports {
+			name := property(p.Ps, "Name")
+			num, err := strconv.Atoi(property(p.Ps, "PortNumber"))
+			if name == "" || err != nil {
+				continue
+			}
+			signals[Endpoint{SID: b.SID, Kind: "out", Index: num}.String()] = name
+		}
 	}
 
 	var edges []Edge
 
 	for _, line := range sys.Lines {
-		var srcSID string
-
 		// 해당 Line의 Src를 찾습니다.
-		for _, p := range line.Ps {
-			if p.Name == "Src" {
-				srcSID = parseSIDFromEndpoint(p.Value)
-				break
-			}
-		}
-		if srcSID == "" {
+		src := parseEndpoint(property(line.Ps, "Src"))
+		if src.SID == "" {
 			continue
 		}
+		tmpl := Edge{
+			Src:    src,
+			Line:   property(line.Ps, "Name"),
+			Signal: signals[src.String()],
+		}
 
 		// 1）메인 Line에는 Dst가 하나 있을 수 있습니다.
-		for _, p := range line.Ps {
-			if p.Name == "Dst" {
-				dstSID := parseSIDFromEndpoint(p.Value)
-				if dstSID != "" {
-					edges = append(edges, Edge{
-						SrcSID: srcSID,
-						DstSID: dstSID,
-					})
-				}
-			}
-		}
+		collectDst(tmpl, line.Ps, &edges)
 
 		// 2）각 Branch에도 Dst가 있을 수 있습니다.
 		for _, br := range line.Branches {
-			collectDstFromBranch(srcSID, br, &edges)
+			collectDstFromBranch(tmpl, br, &edges)
 		}
 	}
 
 	return edges, nil
 }
 
-// Branch 및 그 하위 Branch를 재귀적으로 스캔하여 모든 Dst를 수집하세요
-func collectDstFromBranch(srcSID string, br xmlBranch, edges *[]Edge) {
-	// 현재 Branch 자체의 Dst
-	for _, p := range br.Ps {
-		if p.Name == "Dst" {
-			dstSID := parseSIDFromEndpoint(p.Value)
-			if dstSID != "" {
-				*edges = append(*edges, Edge{
-					SrcSID: srcSID,
-					DstSID: dstSID,
-				})
-			}
+// readSystem은 system_xxx.xml을 읽어 파싱합니다.
+func readSystem(model Model_Reader.Model, file string) (xmlSystem, error) {
+	name := Model_Reader.SystemPath(file)
+	fullPath := model.Location(name)
+
+	var sys xmlSystem
+	data, err := model.ReadFile(name)
+	if err != nil {
+		return sys, fmt.Errorf("XML 읽기 실패 [%s]: %w", fullPath, err)
+	}
+	if err := xml.Unmarshal(data, &sys); err != nil {
+		return sys, fmt.Errorf("XML 파싱 실패 [%s]: %w", fullPath, err)
+	}
+	return sys, nil
+}
+
+// collectDst는 ps의 모든 Dst에 대해 tmpl(Src/Line/Signal)을 복사한 Edge를 추가합니다.
+func collectDst(tmpl Edge, ps []xmlP, edges *[]Edge) {
+	for _, p := range ps {
+		if p.Name != "Dst" {
+			continue
+		}
+		dst := parseEndpoint(p.Value)
+		if dst.SID == "" {
+			continue
 		}
+		e := tmpl
+		e.Dst = dst
+		*edges = append(*edges, e)
 	}
+}
+
+// Branch 및 그 하위 Branch를 재귀적으로 스캔하여 모든 Dst를 수집하세요
+func collectDstFromBranch(tmpl Edge, br xmlBranch, edges *[]Edge) {
+	// 현재 Branch 자체의 Dst
+	collectDst(tmpl, br.Ps, edges)
 
 	// 하위 Branch를 재귀적으로 탐색
 	for _, child := range br.Branches {
-		collectDstFromBranch(srcSID, child, edges)
+		collectDstFromBranch(tmpl, child, edges)
 	}
 }
 
-// "39#out:1" / "66#in:3" / "202#trigger" → "39" / "66" / "202"
-func parseSIDFromEndpoint(ep string) string {
+// "39#out:1" / "66#in:3" / "202#trigger" → {39 out 1} / {66 in 3} / {202 trigger 0}
+func parseEndpoint(ep string) Endpoint {
 	ep = strings.TrimSpace(ep)
 	if ep == "" {
-		return ""
+		return Endpoint{}
+	}
+	idx := strings.Index(ep, "#")
+	if idx < 0 {
+		return Endpoint{SID: ep}
+	}
+	if idx == 0 {
+		return Endpoint{}
+	}
+	res := Endpoint{SID: ep[:idx], Kind: ep[idx+1:]}
+	if i := strings.Index(res.Kind, ":"); i >= 0 {
+		res.Index, _ = strconv.Atoi(res.Kind[i+1:])
+		res.Kind = res.Kind[:i]
+	}
+	return res
+}
+
+// FanOut은 출력 포트(Src Endpoint)별로 연결된 입력 포트 수를 셉니다.
+func FanOut(edges []Edge) map[Endpoint]int {
+	out := make(map[Endpoint]int)
+	for _, e := range edges {
+		out[e.Src]++
+	}
+	return out
+}
+
+// FanIn은 입력 포트(Dst Endpoint)별로 들어오는 선의 수를 셉니다(1보다 크면 잘못 연결된 모델입니다).
+func FanIn(edges []Edge) map[Endpoint]int {
+	in := make(map[Endpoint]int)
+	for _, e := range edges {
+		in[e.Dst]++
+	}
+	return in
+}
+
+// DanglingLine은 끝이 연결되지 않은 선입니다.
+// Src가 비어 있으면 출발 블록이 없는 선이고, OpenEnds는 Dst 없이 끝나는 선(또는 Branch)의 끝 개수입니다.
+type DanglingLine struct {
+	Src      Endpoint
+	Dsts     []Endpoint // 선과 Branch에 연결된 입력 포트(Src가 없는 선의 위치를 찾는 데 사용)
+	Line     string     // Line의 Name
+	OpenEnds int
+}
+
+// AnalyzeDanglingLinesInFile은 특정 system_xxx.xml에서 Src가 없거나 Dst 없이 끝나는 선을 반환합니다.
+func AnalyzeDanglingLinesInFile(model Model_Reader.Model, file string) ([]DanglingLine, error) {
+	sys, err := readSystem(model, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []DanglingLine
+	for _, line := range sys.Lines {
+		dl := DanglingLine{
+			Src:  parseEndpoint(property(line.Ps, "Src")),
+			Line: property(line.Ps, "Name"),
+		}
+		dl.OpenEnds = countOpenEnds(line.Ps, line.Branches)
+		if dl.Src.SID == "" || dl.OpenEnds > 0 {
+			dl.Dsts = lineDsts(line)
+			lines = append(lines, dl)
+		}
+	}
+	return lines, nil
+}
+
+// lineDsts는 Line과 모든 하위 Branch의 Dst를 순서대로 반환합니다.
+func lineDsts(line xmlLine) []Endpoint {
+	var edges []Edge
+	collectDst(Edge{}, line.Ps, &edges)
+	for _, br := range line.Branches {
+		collectDstFromBranch(Edge{}, br, &edges)
+	}
+	dsts := make([]Endpoint, 0, len(edges))
+	for _, e := range edges {
+		dsts = append(dsts, e.Dst)
+	}
+	return dsts
+}
+
+// countOpenEnds는 Line/Branch와 그 하위 Branch에서 Dst도 하위 Branch도 없이 끝나는 끝의 개수를 셉니다.
+func countOpenEnds(ps []xmlP, branches []xmlBranch) int {
+	if len(branches) == 0 {
+		if parseEndpoint(property(ps, "Dst")).SID == "" {
+			return 1
+		}
+		return 0
 	}
-	if idx := strings.Index(ep, "#"); idx > 0 {
-		return ep[:idx]
+	n := 0
+	for _, br := range branches {
+		n += countOpenEnds(br.Ps, br.Branches)
 	}
-	return ep
+	return n
 }
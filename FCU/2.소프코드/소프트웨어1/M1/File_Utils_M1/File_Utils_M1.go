@@ -3,116 +3,93 @@ package File_Utils_M1
 import (
 	"archive/zip"
 	"bufio"
-	"encoding/xml"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Discovery"
+	"FCU_Tools/M1/Model_Lint"
+	"FCU_Tools/M1/Model_Reader"
+	"FCU_Tools/Public_data"
 )
 
-// 1. Windows 경로 읽기: 콘솔에 안내 문구를 출력하고 입력을 받은 뒤, `M1_Public_Data.SrcPath`에 저장합니다.
-func ReadWindowsPath() {
-	if strings.TrimSpace(M1_Public_Data.SrcPath) != "" {
-		return
+// 1. Windows 경로 읽기: ws.SrcPath가 비어 있으면 콘솔에 안내 문구를 출력하고 입력을 받은 뒤, ws.SrcPath에 저장합니다.
+func ReadWindowsPath(ws *M1_Public_Data.Workspace) error {
+	if strings.TrimSpace(ws.SrcPath) != "" {
+		return nil
 	}
 	fmt.Print("모델이 저장된 Windows 경로를 입력하세요： ")
 
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
-		fmt.Println("입력 읽기 실패:", err)
-		return
+		return fmt.Errorf("입력 읽기 실패: %v", err)
 	}
 
 	input = strings.TrimSpace(input)
-	M1_Public_Data.SrcPath = input
+	ws.SrcPath = input
+	return nil
 }
 
-//  2. SrcPath 하위의 각 하위 폴더에서 동일한 이름의 slx 파일을 찾아 BuildDir로 복사합니다.
+//  2. 모델 검색(Model_Discovery)으로 찾은 slx 파일을 SWC 이름으로 BuildDir에 복사합니다.
 //     SrcPath/
-//     ├─ ModelA/  →  ModelA/ModelA.slx  →  BuildDir/ModelA.slx로 복사
-//     ├─ ModelB/  →  ModelB/ModelB.slx  →  BuildDir/ModelB.slx로 복사
+//     ├─ ModelA/ModelA.slx        →  BuildDir/ModelA.slx로 복사
+//     ├─ sub/ModelB_v2.slx (→ ModelB)  →  BuildDir/ModelB.slx로 복사
 //
-// 또한 TxtDir 하위에 동일한 이름의 txt 파일을 생성합니다: ModelA.txt, ModelB.txt
-func CopySlxToBuild() {
-	srcRoot := M1_Public_Data.SrcPath
-	dstRoot := M1_Public_Data.BuildDir
-	txtRoot := M1_Public_Data.TxtDir
-
-	if srcRoot == "" {
-		fmt.Println("SrcPath가 비어 있습니다. 먼저 ReadWindowsPath()를 호출하여 경로를 입력하세요.")
-		return
-	}
+// 일부 모델의 복사가 실패해도 나머지는 계속 처리하며, 실패한 항목의 오류를 모아서 반환합니다.
+func CopySlxToBuild(ws *M1_Public_Data.Workspace, entries []Model_Discovery.Entry) error {
+	dstRoot := ws.BuildDir
 	if dstRoot == "" {
-		fmt.Println("BuildDir이 비어 있습니다. 먼저 SetWorkDir()를 호출하여 작업 공간을 초기화하세요.")
-		return
-	}
-	if txtRoot == "" {
-		fmt.Println("TxtDir이 비어 있습니다. SetWorkDir()가 올바르게 설정되었는지 확인하세요.")
-		return
+		return fmt.Errorf("BuildDir이 비어 있습니다. 먼저 Workspace.Init()를 호출하여 작업 공간을 초기화하세요.")
 	}
 
-	entries, err := os.ReadDir(srcRoot)
-	if err != nil {
-		fmt.Println("SrcPath 디렉터리를 읽을 수 없습니다：", err)
-		return
-	}
+	var errs []error
 
 	for _, e := range entries {
-		if !e.IsDir() {
+		if !strings.EqualFold(filepath.Ext(e.Path), ".slx") {
 			continue
 		}
 
-		folderName := e.Name()
-		slxPath := filepath.Join(srcRoot, folderName, folderName+".slx")
-		if _, err := os.Stat(slxPath); err != nil {
-			// 동일한 이름의 slx 파일이 없으면 건너뜁니다.
-			continue
-		}
-
-		// 대상 slx 파일 경로: BuildDir/동일한이름.slx
-		dstPath := filepath.Join(dstRoot, folderName+".slx")
+		// 대상 slx 파일 경로: BuildDir/<SWC 이름>.slx
+		dstPath := filepath.Join(dstRoot, e.SWC+".slx")
 
 		// slx 파일 복사
-		if err := copyFile(slxPath, dstPath); err != nil {
-			fmt.Printf("복사 실패 [%s] → [%s]：%v\n", slxPath, dstPath, err)
+		if err := copyFile(e.Path, dstPath); err != nil {
+			fmt.Printf("복사 실패 [%s] → [%s]：%v\n", e.Path, dstPath, err)
+			errs = append(errs, fmt.Errorf("복사 실패 [%s]: %v", e.Path, err))
 			continue
 		}
-
-		// TxtDir 아래에 동일한 이름의 txt 파일을 생성합니다.
-		txtPath := filepath.Join(txtRoot, folderName+".txt")
-		f, err := os.Create(txtPath) // 실행할 때마다 재생성/초기화합니다.
-		if err != nil {
-			fmt.Printf("txt 파일을 생성할 수 없습니다. [%s]：%v\n", txtPath, err)
-			continue
-		}
-		_ = f.Close()
 	}
+	return errors.Join(errs...)
 }
 
 //  4. BuildDir 아래의 slx 파일을 동일한 이름의 디렉터리로 압축 해제합니다.
 //     BuildDir/
 //     ├─ ModelA.slx  →  BuildDir/ModelA/...에 압축 해제
 //     ├─ ModelB.slx  →  BuildDir/ModelB/...에 압축 해제
-func UnzipSlxFiles() {
-	buildRoot := M1_Public_Data.BuildDir
+//
+// 일부 파일의 압축 해제가 실패해도 나머지는 계속 처리하며, 실패한 항목의 오류를 모아서 반환합니다.
+func UnzipSlxFiles(ws *M1_Public_Data.Workspace) error {
+	buildRoot := ws.BuildDir
 	if buildRoot == "" {
-		fmt.Println("BuildDir이 비어 있습니다. 먼저 SetWorkDir()를 호출하여 작업 공간을 초기화하세요.")
-		return
+		return fmt.Errorf("BuildDir이 비어 있습니다. 먼저 Workspace.Init()를 호출하여 작업 공간을 초기화하세요.")
 	}
 
 	entries, err := os.ReadDir(buildRoot)
 	if err != nil {
-		fmt.Println("BuildDir 디렉터리를 읽을 수 없습니다:", err)
-		return
+		return fmt.Errorf("BuildDir 디렉터리를 읽을 수 없습니다: %v", err)
 	}
 
+	var errs []error
+
 	for _, e := range entries {
 		if e.IsDir() {
 			continue
@@ -130,11 +107,86 @@ func UnzipSlxFiles() {
 		// 압축 해제 대상 디렉터리가 깨끗한 상태(기존 파일 없음)인지 보장합니다.
 		_ = os.RemoveAll(destDir)
 
-		if err := unzipOne(slxPath, destDir); err != nil {
+		if err := unzipOne(slxPath, destDir, ws.Limits); err != nil {
 			fmt.Printf("압축 해제 실패 [%s] → [%s]：%v\n", slxPath, destDir, err)
+			errs = append(errs, fmt.Errorf("압축 해제 실패 [%s]: %v", slxPath, err))
 			continue
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// OpenModels는 모델 검색(Model_Discovery)으로 찾은 모델을 SWC 이름으로 엽니다.
+//
+//	기본: slx/mdl 파일을 메모리에서 바로 읽습니다(BuildDir에 복사하거나 압축을 풀지 않음).
+//	ws.ExtractToDisk: slx는 기존 방식대로 BuildDir에 복사(CopySlxToBuild)하고 압축을 푼 뒤(UnzipSlxFiles), 풀린 폴더를 읽습니다.
+//	mdl(텍스트 형식)은 두 경우 모두 파싱하여 메모리에서 읽습니다.
+//
+// 일부 모델을 열지 못해도 나머지는 계속 처리하며, 실패한 항목의 오류를 모아서 반환합니다.
+// 반환된 모델은 사용 후 CloseModels로 닫습니다.
+func OpenModels(ws *M1_Public_Data.Workspace, entries []Model_Discovery.Entry) ([]Model_Reader.Model, error) {
+	if ws.ExtractToDisk {
+		return openExtractedModels(ws, entries)
+	}
+
+	var models []Model_Reader.Model
+	var errs []error
+
+	for _, e := range entries {
+		model, err := Model_Reader.Open(e.Path, ws.Limits)
+		if err != nil {
+			fmt.Printf("모델 열기 실패：%v\n", err)
+			errs = append(errs, fmt.Errorf("모델 열기 실패: %v", err))
+			continue
+		}
+		models = append(models, Model_Reader.Rename(model, e.SWC))
+	}
+	return models, errors.Join(errs...)
+}
+
+// 디버그 모드: slx는 BuildDir에 복사하고 압축을 푼 뒤, 풀린 폴더(BuildDir/<SWC>)를 모델로 엽니다.
+func openExtractedModels(ws *M1_Public_Data.Workspace, entries []Model_Discovery.Entry) ([]Model_Reader.Model, error) {
+	var errs []error
+
+	// 찾은 slx 파일을 BuildDir로 복사합니다.
+	if err := CopySlxToBuild(ws, entries); err != nil {
+		errs = append(errs, err)
+	}
+
+	// slx 파일을 BuildDir 아래의 동일한 이름의 디렉터리로 압축 해제합니다.
+	if err := UnzipSlxFiles(ws); err != nil {
+		errs = append(errs, err)
+	}
+
+	var models []Model_Reader.Model
+	for _, e := range entries {
+		if strings.EqualFold(filepath.Ext(e.Path), ".slx") {
+			dir := filepath.Join(ws.BuildDir, e.SWC)
+			if _, err := os.Stat(dir); err != nil {
+				// 복사/압축 해제 실패는 위에서 이미 오류로 모았습니다.
+				continue
+			}
+			models = append(models, Model_Reader.OpenDir(e.SWC, dir))
+			continue
+		}
+
+		// mdl은 압축 파일이 아니므로 풀지 않고 바로 파싱합니다.
+		model, err := Model_Reader.Open(e.Path, ws.Limits)
+		if err != nil {
+			fmt.Printf("모델 열기 실패：%v\n", err)
+			errs = append(errs, fmt.Errorf("모델 열기 실패: %v", err))
+			continue
+		}
+		models = append(models, Model_Reader.Rename(model, e.SWC))
+	}
+	return models, errors.Join(errs...)
+}
+
+// CloseModels는 OpenModels로 연 모델을 모두 닫습니다.
+func CloseModels(models []Model_Reader.Model) {
+	for _, m := range models {
+		_ = m.Close()
+	}
 }
 
 // 간단한 파일 복사 유틸리티
@@ -163,367 +215,356 @@ func copyFile(src, dst string) error {
 }
 
 // 단일 slx(zip) 파일을 destDir에 압축 해제합니다.
-func unzipOne(zipPath, destDir string) error {
+// 공급사 모델을 그대로 분석하므로 다음을 검사합니다.
+//   - destDir 밖을 가리키는 항목("../", 절대 경로, "C:\" 같은 드라이브 경로)은 거부합니다(zip-slip).
+//   - 심볼릭 링크 등 일반 파일이 아닌 항목은 Simulink가 만들지 않으므로 조작된 파일로 보고 거부합니다.
+//   - limits의 항목 수/항목 크기/전체 크기를 넘으면 중단합니다. 헤더의 크기 정보는 조작될 수 있으므로 실제로 쓴 크기로 다시 확인합니다.
+//   - zip 형식이 아니거나 손상된 파일은 그 내용을 오류로 반환합니다.
+//
+// 실패하면 일부만 풀린 destDir를 삭제하여 이후 단계가 불완전한 모델을 읽지 않도록 합니다.
+func unzipOne(zipPath, destDir string, limits M1_Public_Data.ExtractLimits) (err error) {
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("손상되었거나 zip 형식이 아닌 slx 파일입니다: %v", err)
 	}
 	defer r.Close()
 
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(destDir)
+		}
+	}()
+
+	if limits.MaxEntries > 0 && len(r.File) > limits.MaxEntries {
+		return fmt.Errorf("압축 파일의 항목 수(%d)가 제한(%d)을 초과합니다", len(r.File), limits.MaxEntries)
+	}
+
+	var total int64
 	for _, f := range r.File {
-		targetPath := filepath.Join(destDir, f.Name)
+		targetPath, err := safeExtractPath(destDir, f.Name)
+		if err != nil {
+			return err
+		}
 
+		mode := f.Mode()
 		// 디렉터리
-		if f.FileInfo().IsDir() {
+		if mode.IsDir() {
 			if err := os.MkdirAll(targetPath, 0755); err != nil {
 				return err
 			}
 			continue
 		}
+		// 심볼릭 링크, 장치 파일 등은 디스크에 만들지 않습니다.
+		if !mode.IsRegular() {
+			return fmt.Errorf("일반 파일이 아닌 항목은 허용되지 않습니다: %q (%v)", f.Name, mode.Type())
+		}
+
+		if limits.MaxEntryBytes > 0 && f.UncompressedSize64 > uint64(limits.MaxEntryBytes) {
+			return fmt.Errorf("항목 [%s]의 크기(%d바이트)가 제한(%d바이트)을 초과합니다", f.Name, f.UncompressedSize64, limits.MaxEntryBytes)
+		}
 
 		// 상위 디렉터리가 존재하도록 보장합니다.
 		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
 			return err
 		}
 
-		rc, err := f.Open()
+		n, err := extractFile(f, targetPath, limits, total)
+		total += n
 		if err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		outFile, err := os.Create(targetPath)
-		if err != nil {
-			rc.Close()
-			return err
-		}
+// safeExtractPath는 압축 파일 안의 항목 이름을 destDir 아래의 경로로 바꿉니다.
+// 절대 경로이거나 destDir 밖을 가리키면 오류를 반환합니다.
+// 드라이브 경로("C:\x")는 Windows가 아닌 곳에서도 거부하여, 어느 OS에서 풀어도 결과가 같도록 합니다.
+func safeExtractPath(destDir, name string) (string, error) {
+	// zip 항목 이름은 "/" 구분자를 사용하지만, Windows에서 만든 파일은 "\"가 섞여 있을 수 있습니다.
+	clean := strings.ReplaceAll(name, "\\", "/")
+	if clean == "" || strings.HasPrefix(clean, "/") || filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || hasDriveLetter(clean) {
+		return "", fmt.Errorf("허용되지 않는 항목 경로입니다(절대 경로): %q", name)
+	}
 
-		if _, err := io.Copy(outFile, rc); err != nil {
-			outFile.Close()
-			rc.Close()
-			return err
+	targetPath := filepath.Join(destDir, filepath.FromSlash(clean))
+	rel, err := filepath.Rel(destDir, targetPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("허용되지 않는 항목 경로입니다(압축 해제 폴더 밖을 가리킴): %q", name)
+	}
+	return targetPath, nil
+}
+
+// hasDriveLetter는 이름이 "C:"처럼 드라이브 문자로 시작하는지 반환합니다.
+func hasDriveLetter(name string) bool {
+	if len(name) < 2 || name[1] != ':' {
+		return false
+	}
+	c := name[0] | 0x20 // 소문자로
+	return 'a' <= c && c <= 'z'
+}
+
+// extractFile은 항목 하나를 targetPath에 쓰고 실제로 쓴 바이트 수를 반환합니다.
+// written은 같은 압축 파일에서 지금까지 쓴 전체 바이트 수입니다.
+func extractFile(f *zip.File, targetPath string, limits M1_Public_Data.ExtractLimits, written int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("항목 [%s]을 열 수 없습니다(손상된 압축 파일): %v", f.Name, err)
+	}
+	defer rc.Close()
+
+	outFile, err := os.Create(targetPath)
+	if err != nil {
+		return 0, err
+	}
+	defer outFile.Close()
+
+	// 항목 제한과 남은 전체 제한 중 작은 값까지만 읽습니다. 1바이트를 더 읽어 초과 여부를 판단합니다.
+	limit := int64(-1)
+	if limits.MaxEntryBytes > 0 {
+		limit = limits.MaxEntryBytes
+	}
+	if limits.MaxTotalBytes > 0 {
+		if remain := limits.MaxTotalBytes - written; limit < 0 || remain < limit {
+			limit = remain
 		}
+	}
 
-		outFile.Close()
-		rc.Close()
+	var src io.Reader = rc
+	if limit >= 0 {
+		src = io.LimitReader(rc, limit+1)
 	}
-	return nil
+	n, err := io.Copy(outFile, src)
+	if err != nil {
+		return n, fmt.Errorf("항목 [%s] 압축 해제 실패(손상된 압축 파일): %v", f.Name, err)
+	}
+	if limit >= 0 && n > limit {
+		if limits.MaxEntryBytes > 0 && n > limits.MaxEntryBytes {
+			return n, fmt.Errorf("항목 [%s]의 실제 크기가 제한(%d바이트)을 초과합니다", f.Name, limits.MaxEntryBytes)
+		}
+		return n, fmt.Errorf("압축 해제한 전체 크기가 제한(%d바이트)을 초과합니다", limits.MaxTotalBytes)
+	}
+	return n, nil
 }
 
 // ===================== M1 LDI 생성 관련 =====================
 
-// txt에서 파싱한 노드 정보를 저장/사용하기 위함
+// 분석 결과(Block)에서 만든 노드 정보를 저장/사용하기 위함
 type m1Node struct {
 	Level          int
 	Name           string
 	SID            string
-	Father         string
-	Ports          int     // 현재 노드의 포트 개수(virtual port 포함)
-	CSPorts        int     // L1의 C-S 포트 개수(해당 레벨에만 적용)
-	ChildCount     int     // 직접 하위 노드 개수
-	ChildPorts     int     // 직접 하위 노드들의 포트 수 합계
-	EffectivePorts float64 // L1: 가중 포트 수, 기타 레벨: Ports와 동일
-	Coverage       float64 // 계산된 m1 값
-
-	// “[Lx Connect] Name:XXX SID=.. strength=N”에서 파싱
+	Path           string         // SID 경로(노드 식별용)
+	Father         string         // 부모 노드 이름(표시용)
+	FatherPath     string         // 부모 노드의 SID 경로
+	Masked         bool           // 마스크가 적용된 블록
+	Atomic         bool           // 원자 단위 SubSystem
+	LibraryLink    string         // 라이브러리 링크의 SourceBlock
+	ModelReference string         // 참조 모델 이름
+	Ports          int            // 현재 노드의 포트 개수(virtual port 포함)
+	CSPorts        int            // 현재 노드의 C-S 포트 개수(현재는 L1에만 존재)
+	PortKinds      map[string]int // S-R 포트 종류(Public_data.M1PortKinds)별 개수
+	ChildCount     int            // 직접 하위 노드 개수
+	ChildPorts     int            // 직접 하위 노드들의 포트 수 합계
+	EffectivePorts float64        // 레벨 규칙의 C-S 포트/포트 종류별 가중치를 적용한 포트 수(가중치가 모두 1이면 Ports와 동일)
+	Coverage       float64        // 계산된 m1 값
+	Dangling       int            // 이 노드의 SubSystem 안에서 찾은 연결 결함 수(Model_Lint, system_root의 결함은 첫 번째 L1 노드에 포함)
+
+	// Block.Connects에서 가져옴
 	// key=providerName, value=strength(동일 이름은 누적)
 	Uses map[string]int
 }
 
-// LDI XML 구조
-type ldiProperty struct {
-	XMLName xml.Name `xml:"property"`
-	Name    string   `xml:"name,attr"`
-	Value   string   `xml:",chardata"`
-}
-
-// <uses provider="..." strength="..."/>
-type ldiUses struct {
-	XMLName  xml.Name `xml:"uses"`
-	Provider string   `xml:"provider,attr"`
-	Strength string   `xml:"strength,attr,omitempty"`
-}
-
-type ldiElement struct {
-	XMLName  xml.Name      `xml:"element"`
-	Name     string        `xml:"name,attr"`
-	Uses     []ldiUses     `xml:"uses"`
-	Property []ldiProperty `xml:"property"`
-}
-
-type ldiRoot struct {
-	XMLName xml.Name     `xml:"ldi"`
-	Items   []ldiElement `xml:"element"`
-}
-
-//  6. TxtDir 하위의 txt 파일을 기반으로 해당 ldi.xml을 생성합니다.
-//     예: TurnLight.txt → TurnLight.ldi.xml
+//  6. 모델별 분석 결과(블록 트리)로 해당 ldi.xml을 생성합니다.
+//     예: TurnLight → LDIDir/TurnLight.ldi.xml
 //     규칙: N단계가 존재할 경우 1..N-1 단계까지만 m1을 계산하고 출력하며, 최하위 N단계는 출력하지 않습니다.
-//     또한 TxtDir 하위에 XXX_m1.txt를 생성하여 각 레벨별 Ports / 하위 노드 개수 / 하위 포트 수를 요약합니다.
-func GenerateM1LDIFromTxt() {
-	txtRoot := M1_Public_Data.TxtDir
-	ldiRoot := M1_Public_Data.LDIDir
+//     ws.DumpTxt이면 TxtDir 하위에 사람이 읽을 수 있는 <Model>.txt와, 각 레벨별 Ports / 하위 노드 개수 / 하위 포트 수를 요약한 <Model>_m1.txt를 생성합니다.
+//     ws.DumpJSON이면 TxtDir 하위에 블록 트리를 그대로 담은 <Model>.json을 생성합니다.
+//     일부 모델의 처리가 실패해도 나머지는 계속 처리하며, 실패한 항목의 오류를 모아서 반환합니다.
+func GenerateM1LDI(ws *M1_Public_Data.Workspace, results []*M1_Public_Data.ModelResult, rules Public_data.M1LevelRules) error {
+	ldiRoot := ws.LDIDir
+	txtRoot := ws.TxtDir
 
-	if txtRoot == "" || ldiRoot == "" {
-		fmt.Println("TxtDir 또는 LDIDir이 비어 있습니다. SetWorkDir()가 올바르게 설정되었는지 확인하세요.")
-		return
+	if ldiRoot == "" {
+		return fmt.Errorf("LDIDir이 비어 있습니다. Workspace.Init()가 올바르게 설정되었는지 확인하세요.")
 	}
-
-	entries, err := os.ReadDir(txtRoot)
-	if err != nil {
-		fmt.Println("TxtDir 읽기 실패:", err)
-		return
+	if (ws.DumpTxt || ws.DumpJSON) && txtRoot == "" {
+		return fmt.Errorf("TxtDir이 비어 있습니다. Workspace.Init()가 올바르게 설정되었는지 확인하세요.")
 	}
 
-	// 确保 LDI 目录存在
+	// LDI 디렉터리가 존재하도록 보장합니다.
 	if err := os.MkdirAll(ldiRoot, 0755); err != nil {
-		fmt.Println("LDI 디렉터리 생성 실패:", err)
-		return
+		return fmt.Errorf("LDI 디렉터리 생성 실패: %v", err)
 	}
 
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		if strings.ToLower(filepath.Ext(name)) != ".txt" {
-			continue
-		}
+	var errs []error
 
-		txtPath := filepath.Join(txtRoot, name)
-		modelName := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, res := range results {
+		modelName := res.Name
 
-		nodes, err := parseM1NodesFromTxt(txtPath)
-		if err != nil {
-			fmt.Printf("txt 파싱 실패 [%s]: %v\n", txtPath, err)
-			continue
+		if ws.DumpTxt {
+			txtPath := filepath.Join(txtRoot, modelName+".txt")
+			if err := writeModelTxt(txtPath, res.Blocks); err != nil {
+				fmt.Printf("txt 작성 실패 [%s]: %v\n", txtPath, err)
+				errs = append(errs, fmt.Errorf("txt 작성 실패 [%s]: %v", txtPath, err))
+			}
 		}
+		if ws.DumpJSON {
+			jsonPath := filepath.Join(txtRoot, modelName+".json")
+			if err := writeModelJSON(jsonPath, res.Blocks); err != nil {
+				fmt.Printf("JSON 작성 실패 [%s]: %v\n", jsonPath, err)
+				errs = append(errs, fmt.Errorf("JSON 작성 실패 [%s]: %v", jsonPath, err))
+			}
+		}
+
+		nodes := nodesFromBlocks(res.Blocks)
 		if len(nodes) == 0 {
-			fmt.Printf("txt에서 노드를 파싱하지 못했습니다. [%s]\n", txtPath)
+			fmt.Printf("분석된 노드가 없습니다. [%s]\n", modelName)
 			continue
 		}
 
-		computeM1ForNodes(nodes)
-		// ldi.xml을 생성합니다(여기서 txt 파일명을 전달하여 element name의 접두어를 치환하는 데 사용합니다).
+		computeM1ForNodes(nodes, rules)
+		countLintForNodes(nodes, res.Lint)
+		// ldi.xml을 생성합니다(여기서 모델명을 전달하여 element name의 접두어를 치환하는 데 사용합니다).
 		ldiPath := filepath.Join(ldiRoot, modelName+".ldi.xml")
 		if err := writeM1LDI(ldiPath, modelName, nodes); err != nil {
 			fmt.Printf("LDI 작성 실패 [%s]: %v\n", ldiPath, err)
+			errs = append(errs, fmt.Errorf("LDI 작성 실패 [%s]: %v", ldiPath, err))
 			// 중단하지 않고, 계속해서 m1.txt를 생성합니다.
 		} else {
 			fmt.Printf("📄 M1 지표 계산 완료: %s\n", ldiPath)
 		}
 
-		statsPath := filepath.Join(txtRoot, modelName+"_m1.txt")
-		if err := writeM1StatsTxt(statsPath, nodes); err != nil {
-			fmt.Printf("m1 통계 작성 실패 [%s]: %v\n", statsPath, err)
+		if ws.DumpTxt {
+			statsPath := filepath.Join(txtRoot, modelName+"_m1.txt")
+			if err := writeM1StatsTxt(statsPath, nodes); err != nil {
+				fmt.Printf("m1 통계 작성 실패 [%s]: %v\n", statsPath, err)
+				errs = append(errs, fmt.Errorf("m1 통계 작성 실패 [%s]: %v", statsPath, err))
+			}
 		}
 	}
+	return errors.Join(errs...)
 }
 
-// 하나의 txt를 파싱하여 모든 [Lx] block과 [Lx Port]/[Lx virtual Port]를 모두 추출합니다.
-func parseM1NodesFromTxt(txtPath string) ([]*m1Node, error) {
-	f, err := os.Open(txtPath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-
-	var (
-		nodes   []*m1Node
-		curNode *m1Node
-	)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
+// nodesFromBlocks는 분석 결과의 블록을 m1 계산용 노드로 변환합니다.
+// 포트 수는 virtual port를 포함하며, C-S 포트 수와 S-R 포트 종류별 개수는 가중치 계산에 사용합니다.
+func nodesFromBlocks(blocks []*M1_Public_Data.Block) []*m1Node {
+	nodes := make([]*m1Node, 0, len(blocks))
+	for _, b := range blocks {
+		if b.Name == "" {
 			continue
 		}
-
-		// Block 행: "[L"로 시작하고 앞에 탭(Tab) 들여쓰기가 없는 행
-		if strings.HasPrefix(line, "[L") {
-			trim := strings.TrimSpace(line)
-			levelRe := regexp.MustCompile(`^\[L(\d+)\]`)
-			m := levelRe.FindStringSubmatch(trim)
-			if len(m) >= 2 {
-				level, name, sid, father, ok := parseBlockLineInfo(trim)
-				if !ok {
-					continue
-				}
-				node := &m1Node{
-					Level:  level,
-					Name:   name,
-					SID:    sid,
-					Father: father,
-					Uses:   make(map[string]int),
-				}
-				nodes = append(nodes, node)
-				curNode = node
-				continue
-			}
-		}
-
-		// 포트 행 예: \t[L1 Port] 또는 \t[L2 virtual Port]
-		if strings.HasPrefix(line, "\t[L") {
-			trim := strings.TrimLeft(line, "\t")
-			endIdx := strings.Index(trim, "]")
-			if endIdx <= 0 {
-				continue
-			}
-			header := trim[1:endIdx] // e.g. "L2 Port" / "L2 virtual Port" / "L2 Connect"
-			fields := strings.Fields(header)
-			if len(fields) < 2 {
-				continue
-			}
-
-			levelStr := strings.TrimPrefix(fields[0], "L")
-			level, err := strconv.Atoi(levelStr)
-			if err != nil {
-				continue
-			}
-
-			// Connect 행: Uses에 기록(Ports에는 포함하지 않음)
-			if strings.EqualFold(fields[1], "Connect") {
-				if curNode != nil && curNode.Level == level {
-					provider, strength, ok := parseConnectLine(trim)
-					if ok {
-						if curNode.Uses == nil {
-							curNode.Uses = make(map[string]int)
-						}
-						curNode.Uses[provider] += strength
-					}
-				}
+		n := &m1Node{
+			Level:      b.Level,
+			Name:       b.Name,
+			SID:        b.SID,
+			Path:       b.Path,
+			Father:     b.Father,
+			FatherPath: b.FatherPath,
+
+			Masked:         b.Masked,
+			Atomic:         b.Atomic,
+			LibraryLink:    b.LibraryLink,
+			ModelReference: b.ModelReference,
+			Ports:          len(b.Ports),
+			PortKinds:      make(map[string]int),
+			Uses:           make(map[string]int),
+		}
+		for _, p := range b.Ports {
+			if p.PortType == "C-S" {
+				n.CSPorts++
 				continue
 			}
-
-			// Port / virtual Port 행: Ports에만 포함
-			lv2, portType, ok := parsePortLineLevelAndType(header, trim)
-			if !ok {
-				continue
+			kind := p.Kind
+			if kind == "" {
+				kind = Public_data.M1PortKindData
 			}
-
-			if curNode != nil && curNode.Level == lv2 {
-				curNode.Ports++
-				if portType == "C-S" {
-					curNode.CSPorts++
-				}
+			n.PortKinds[kind]++
+		}
+		for _, c := range b.Connects {
+			if c.Name != "" && c.Strength > 0 {
+				n.Uses[c.Name] += c.Strength
 			}
 		}
+		nodes = append(nodes, n)
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return nodes, nil
+	return nodes
 }
 
-// [L2 Connect] Name:CL1CM2CLS2  SID=12  strength=1
-func parseConnectLine(fullLine string) (provider string, strength int, ok bool) {
-	if nameIdx := strings.Index(fullLine, "Name:"); nameIdx >= 0 {
-		after := fullLine[nameIdx+len("Name:"):]
-		sidIdx := strings.Index(after, "SID=")
-		if sidIdx > 0 {
-			provider = strings.TrimSpace(after[:sidIdx])
+// writeModelTxt는 블록 트리를 사람이 읽을 수 있는 txt로 출력합니다(분석에는 사용하지 않는 덤프입니다).
+// 형식：
+// [Lx] Name: <BlockName>	BlockType=<BlockType>	SID=<SID> [FatherNode=xxx]
+//
+//	[Lx Connect] Name:<SubSystem>	SID=<SID>	strength=N
+//	[Lx Port] Name: <Port>	BlockType=<In/Outport>	SID=<SID> [PortType=S-R] [Kind=trigger]
+//	[Lx virtual Port] Name: <BlockA->BlockB[_n]>	BlockType=<In/Outport>	SID=<69->147> ...
+func writeModelTxt(txtPath string, blocks []*M1_Public_Data.Block) error {
+	var b strings.Builder
+	for _, blk := range blocks {
+		level := blk.Level
+
+		// 먼저 Block 자체 정보를 출력하고, FatherNode를 함께 표시합니다(2층부터).
+		if blk.Father != "" && level >= 2 {
+			fmt.Fprintf(&b,
+				"[L%d] Name: %-10s\tBlockType=%-10s\tSID=%-10s\tFatherNode=%-10s\n",
+				level, blk.Name, blk.BlockType, blk.SID, blk.Father,
+			)
 		} else {
-			provider = strings.TrimSpace(after)
+			fmt.Fprintf(&b,
+				"[L%d] Name: %s\tBlockType=%s\tSID=%s\n",
+				level, blk.Name, blk.BlockType, blk.SID,
+			)
 		}
-	}
 
-	low := strings.ToLower(fullLine)
-	if stIdx := strings.Index(low, "strength="); stIdx >= 0 {
-		after := low[stIdx+len("strength="):]
-		stFields := strings.Fields(after)
-		if len(stFields) > 0 {
-			v, err := strconv.Atoi(strings.TrimSpace(stFields[0]))
-			if err == nil {
-				strength = v
-			}
+		for _, c := range blk.Connects {
+			fmt.Fprintf(&b,
+				"\t[L%d Connect] Name:%-40s\tSID=%-10s\tstrength=%d\n",
+				level, c.Name, c.SID, c.Strength,
+			)
 		}
-	}
 
-	if provider == "" || strength <= 0 {
-		return "", 0, false
-	}
-	return provider, strength, true
-}
-
-// [L2 Port] 또는 [L2 virtual Port] 같은 문자열에서 Level을 파싱합니다.
-// 또한 한 줄 전체에서 PortType을 파싱합니다( C-S 포트 식별 용도로만 사용 ).
-func parsePortLineLevelAndType(header string, fullLine string) (int, string, bool) {
-	fields := strings.Fields(header) // ["L2","Port"] / ["L2","virtual","Port"] / ["L2","Connect"]
-	if len(fields) < 2 {
-		return 0, "", false
-	}
-	if fields[len(fields)-1] != "Port" {
-		return 0, "", false
-	}
-
-	levelStr := strings.TrimPrefix(fields[0], "L")
-	level, err := strconv.Atoi(levelStr)
-	if err != nil {
-		return 0, "", false
-	}
+		for _, p := range blk.Ports {
+			// 가상 포트 여부에 따라 다른 태그를 선택합니다.
+			label := "Port"
+			if p.Virtual {
+				label = "virtual Port"
+			}
 
-	portType := ""
-	if idx := strings.Index(fullLine, "PortType="); idx >= 0 {
-		rest := fullLine[idx+len("PortType="):]
-		ptFields := strings.Fields(rest)
-		if len(ptFields) > 0 {
-			portType = strings.TrimSpace(ptFields[0])
+			// L1에서만 PortType을 출력하고, L2 및 이후에는 PortType을 출력하지 않습니다.
+			if level == 1 {
+				fmt.Fprintf(&b,
+					"\t[L%d %s] Name: %-40s\tBlockType=%-10s\tSID=%-10s\tPortType=%-10s",
+					level, label, p.Name, p.BlockType, p.SID, p.PortType,
+				)
+			} else {
+				fmt.Fprintf(&b,
+					"\t[L%d %s] Name:%-40s\tBlockType=%-10s\tSID=%-10s",
+					level, label, p.Name, p.BlockType, p.SID,
+				)
+			}
+			// S-R 포트는 데이터가 아닌 종류(trigger, bus_element 등)만 표시합니다.
+			if p.Kind != "" && p.Kind != Public_data.M1PortKindData {
+				fmt.Fprintf(&b, "\tKind=%s", p.Kind)
+			}
+			b.WriteString("\n")
 		}
 	}
-	return level, portType, true
+	return os.WriteFile(txtPath, []byte(b.String()), 0644)
 }
 
-// 다음과 같은 형식을 파싱합니다:
-// [L2] Name: HazardCtrlLogic	BlockType=SubSystem	SID=66       	FatherNode=TurnLight_Runnable_10ms_sys
-func parseBlockLineInfo(trim string) (int, string, string, string, bool) {
-	levelRe := regexp.MustCompile(`^\[L(\d+)\]`)
-	m := levelRe.FindStringSubmatch(trim)
-	if len(m) < 2 {
-		return 0, "", "", "", false
-	}
-	level, err := strconv.Atoi(m[1])
+// writeModelJSON은 블록 트리를 JSON으로 출력합니다(분석에는 사용하지 않는 덤프입니다).
+func writeModelJSON(jsonPath string, blocks []*M1_Public_Data.Block) error {
+	data, err := json.MarshalIndent(blocks, "", "  ")
 	if err != nil {
-		return 0, "", "", "", false
-	}
-
-	name := ""
-	if nameIdx := strings.Index(trim, "Name:"); nameIdx >= 0 {
-		after := trim[nameIdx+len("Name:"):]
-		btIdx := strings.Index(after, "BlockType=")
-		if btIdx > 0 {
-			name = strings.TrimSpace(after[:btIdx])
-		} else {
-			name = strings.TrimSpace(after)
-		}
-	}
-
-	sid := ""
-	if sidIdx := strings.Index(trim, "SID="); sidIdx >= 0 {
-		after := trim[sidIdx+len("SID="):]
-		sidFields := strings.Fields(after)
-		if len(sidFields) > 0 {
-			sid = sidFields[0]
-		}
-	}
-
-	father := ""
-	if faIdx := strings.Index(trim, "FatherNode="); faIdx >= 0 {
-		after := trim[faIdx+len("FatherNode="):]
-		faFields := strings.Fields(after)
-		if len(faFields) > 0 {
-			father = faFields[0]
-		}
-	}
-
-	if name == "" {
-		return 0, "", "", "", false
+		return err
 	}
-	return level, name, sid, father, true
+	return os.WriteFile(jsonPath, data, 0644)
 }
 
-func computeM1ForNodes(nodes []*m1Node) {
+// rules: 레벨별 규칙이며, 각 노드의 C-S 포트 1개에는 해당 레벨 규칙의 가중치를 줍니다
+// (기본: L1은 설정 파일의 m1.cs_port_weight(기본 1.2), 그 외 레벨은 1).
+// S-R 포트는 종류별로 레벨 규칙의 port_kind_weights를 적용합니다(지정하지 않은 종류는 1).
+func computeM1ForNodes(nodes []*m1Node, rules Public_data.M1LevelRules) {
 	if len(nodes) == 0 {
 		return
 	}
@@ -533,14 +574,10 @@ func computeM1ForNodes(nodes []*m1Node) {
 		if n.Level > maxLevel {
 			maxLevel = n.Level
 		}
-		if n.Level == 1 {
-			normalPorts := n.Ports - n.CSPorts
-			if normalPorts < 0 {
-				normalPorts = 0
-			}
-			n.EffectivePorts = float64(normalPorts) + float64(n.CSPorts)*1.2
-		} else {
-			n.EffectivePorts = float64(n.Ports)
+		rule := rules.ForLevel(n.Level)
+		n.EffectivePorts = float64(n.CSPorts) * rule.CSPortWeight
+		for kind, c := range n.PortKinds {
+			n.EffectivePorts += float64(c) * rule.PortKindWeight(kind)
 		}
 	}
 
@@ -563,7 +600,8 @@ func computeM1ForNodes(nodes []*m1Node) {
 
 		var realChildren []*m1Node
 		for _, c := range children {
-			if c.Father == n.Name {
+			// 이름이 아니라 SID 경로로 부모를 찾으므로, 다른 부모 아래의 같은 이름 노드가 섞이지 않습니다.
+			if c.FatherPath == n.Path {
 				realChildren = append(realChildren, c)
 			}
 		}
@@ -581,40 +619,50 @@ func computeM1ForNodes(nodes []*m1Node) {
 			continue
 		}
 
-		if n.Level == 1 {
-			n.Coverage = n.EffectivePorts * float64(n.ChildCount) * float64(n.ChildPorts)
-		} else {
-			n.Coverage = float64(n.Ports) * float64(n.ChildCount) * float64(n.ChildPorts)
+		n.Coverage = n.EffectivePorts * float64(n.ChildCount) * float64(n.ChildPorts)
+	}
+}
+
+// countLintForNodes는 system 파일별 연결 결함 수를 그 system의 부모 노드에 기록합니다.
+// system_root에는 부모 노드가 없으므로, 모델 요소로 먼저 작성되는 L1 노드(이름, SID 경로 순으로 첫 번째) 하나에만 셉니다.
+// L1이 여러 개여도 노드별 결함 수의 합은 Model_Lint.Total과 같습니다.
+func countLintForNodes(nodes []*m1Node, systems []M1_Public_Data.LintSystem) {
+	counts := Model_Lint.CountByFatherPath(systems)
+	var root *m1Node
+	for _, n := range nodes {
+		n.Dangling = counts[n.Path]
+		if n.Level == 1 && (root == nil || n.Name < root.Name || (n.Name == root.Name && n.Path < root.Path)) {
+			root = n
 		}
 	}
+	if root != nil {
+		root.Dangling += counts[""]
+	}
 }
 
 // 레벨(계층) 이름 구성：
 // L1: Name
 // L2: Father.Name  => L1.Name + "." + L2.Name
 // L3: L1.Name + "." + L2.Name + "." + L3.Name
+// 부모는 SID 경로(FatherPath)로 찾고, 이름은 표시에만 사용합니다.
 func buildHierNameForNode(n *m1Node, all []*m1Node) string {
-	if n.Level <= 1 || n.Father == "" {
+	if n.Level <= 1 || n.FatherPath == "" {
 		return n.Name
 	}
 
-	type key struct {
-		Level int
-		Name  string
-	}
-	index := make(map[key]*m1Node)
+	index := make(map[string]*m1Node)
 	for _, x := range all {
-		index[key{Level: x.Level, Name: x.Name}] = x
+		index[x.Path] = x
 	}
 
 	var chain []*m1Node
 	cur := n
 	for cur != nil {
 		chain = append(chain, cur)
-		if cur.Level == 1 || cur.Father == "" {
+		if cur.Level == 1 || cur.FatherPath == "" {
 			break
 		}
-		parent, ok := index[key{Level: cur.Level - 1, Name: cur.Father}]
+		parent, ok := index[cur.FatherPath]
 		if !ok {
 			break
 		}
@@ -672,7 +720,7 @@ func qualifyProviderByElementPath(elementName, provider string) string {
 // nodes를 하나의 ldi.xml 파일로 작성합니다.
 // 주의: 1..(maxLevel-1) 레벨의 노드만 출력하며, 최하위 레벨(Level=maxLevel) 노드는 아예 작성하지 않습니다.
 func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node) error {
-	var root ldiRoot
+	root := LDI_Model.New()
 
 	maxLevel := 0
 	for _, n := range nodes {
@@ -697,7 +745,10 @@ func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node) error {
 		if list[i].Node.Level != list[j].Node.Level {
 			return list[i].Node.Level < list[j].Node.Level
 		}
-		return list[i].Path < list[j].Path
+		if list[i].Path != list[j].Path {
+			return list[i].Path < list[j].Path
+		}
+		return list[i].Node.Path < list[j].Node.Path
 	})
 
 	for _, nn := range list {
@@ -705,14 +756,22 @@ func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node) error {
 		// ✅ ldi.xml 생성 시 name의 첫 번째 구간을 txt 파일명으로 치환합니다.
 		name := replaceElementPrefixWithTxtName(nn.Path, modelName)
 
-		el := ldiElement{
-			Name: name,
-			Property: []ldiProperty{
-				{
-					Name:  "coverage.m1",
-					Value: fmt.Sprintf("%.4f", n.Coverage),
-				},
-			},
+		el := root.AddElement(name)
+		el.AddProperty("coverage.m1", fmt.Sprintf("%.4f", n.Coverage))
+		el.AddProperty(Model_Lint.PropDangling, strconv.Itoa(n.Dangling))
+
+		// 마스크/원자 SubSystem과 라이브러리 링크/참조 모델을 표시합니다.
+		if n.Masked {
+			el.AddProperty("m1.masked", "true")
+		}
+		if n.Atomic {
+			el.AddProperty("m1.atomic", "true")
+		}
+		if n.LibraryLink != "" {
+			el.AddProperty("m1.library_link", n.LibraryLink)
+		}
+		if n.ModelReference != "" {
+			el.AddProperty("m1.model_reference", n.ModelReference)
 		}
 
 		//  <uses provider="..." strength="..."/>
@@ -728,26 +787,12 @@ func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node) error {
 				if s <= 0 {
 					continue
 				}
-				el.Uses = append(el.Uses, ldiUses{
-					Provider: qualifyProviderByElementPath(name, p),
-					Strength: strconv.Itoa(s),
-				})
+				el.AddUses(qualifyProviderByElementPath(name, p), s)
 			}
 		}
-
-		root.Items = append(root.Items, el)
 	}
 
-	out, err := xml.MarshalIndent(root, "", "  ")
-	if err != nil {
-		return fmt.Errorf("LDI XML 직렬화 실패: %v", err)
-	}
-
-	content := append([]byte(xml.Header), out...)
-	if err := os.WriteFile(ldiPath, content, 0644); err != nil {
-		return fmt.Errorf("LDI 파일 쓰기 실패: %v", err)
-	}
-	return nil
+	return root.Save(ldiPath)
 }
 
 // XXX_m1.txt를 생성하여 각 레벨 노드별로 ‘자체 포트 수’, ‘하위 노드 개수’, ‘하위 노드 포트 총합’을 요약합니다.
@@ -785,11 +830,12 @@ func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
 
 		if lv == 1 {
 			line := fmt.Sprintf(
-				"[L1] Name: %s\tL1Ports(Weighted)=%.1f\tL2Count=%d\tL2Ports=%d\n",
+				"[L1] Name: %s\tL1Ports(Weighted)=%.1f\tL2Count=%d\tL2Ports=%d\tPortKinds=%s\n",
 				n.Name,
 				n.EffectivePorts,
 				n.ChildCount,
 				n.ChildPorts,
+				portKindsText(n.PortKinds),
 			)
 			if _, err := f.WriteString(line); err != nil {
 				return err
@@ -797,12 +843,13 @@ func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
 		} else {
 			nextLevel := lv + 1
 			line := fmt.Sprintf(
-				"[L%d] Name: %s\tL%dPorts=%d\tL%dCount=%d\tL%dPorts=%d\n",
+				"[L%d] Name: %s\tL%dPorts=%d\tL%dCount=%d\tL%dPorts=%d\tPortKinds=%s\n",
 				lv,
 				n.Name,
 				lv, n.Ports,
 				nextLevel, n.ChildCount,
 				nextLevel, n.ChildPorts,
+				portKindsText(n.PortKinds),
 			)
 			if _, err := f.WriteString(line); err != nil {
 				return err
@@ -812,3 +859,17 @@ func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
 
 	return nil
 }
+
+// portKindsText는 S-R 포트 종류별 개수를 "data:3,trigger:1" 형식으로 만듭니다(Public_data.M1PortKinds 순서, 0개인 종류는 생략).
+func portKindsText(kinds map[string]int) string {
+	var parts []string
+	for _, k := range Public_data.M1PortKinds {
+		if c := kinds[k]; c > 0 {
+			parts = append(parts, fmt.Sprintf("%s:%d", k, c))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ",")
+}
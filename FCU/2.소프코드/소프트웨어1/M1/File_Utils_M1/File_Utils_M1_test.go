@@ -0,0 +1,172 @@
+package File_Utils_M1
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Lint"
+	"FCU_Tools/Public_data"
+)
+
+// virtualPortBlocks는 L1 블록 Top(포트 1개) 아래의 L2 SubSystem A, B를 만듭니다.
+// withVirtual이면 A→B의 블록-블록 선 두 개에 대해 Port_Analysis가 만드는 가상 포트(A: Outport 2개, B: Inport 2개)를 붙입니다.
+func virtualPortBlocks(withVirtual bool) []*M1_Public_Data.Block {
+	top := &M1_Public_Data.Block{Level: 1, Name: "Top", SID: "1", BlockType: "SubSystem", Path: "1",
+		Ports: []M1_Public_Data.Port{{Name: "In1", SID: "2", BlockType: "Inport", PortType: "S-R", Kind: Public_data.M1PortKindData}}}
+	a := &M1_Public_Data.Block{Level: 2, Name: "A", SID: "66", BlockType: "SubSystem", Path: "1/66", Father: "Top", FatherPath: "1"}
+	b := &M1_Public_Data.Block{Level: 2, Name: "B", SID: "69", BlockType: "SubSystem", Path: "1/69", Father: "Top", FatherPath: "1"}
+	if withVirtual {
+		for _, name := range []string{"A->B_1", "A->B_2"} {
+			a.Ports = append(a.Ports, M1_Public_Data.Port{Name: name, SID: "66->69", BlockType: "Outport", PortType: "S-R", Kind: Public_data.M1PortKindData, Virtual: true})
+			b.Ports = append(b.Ports, M1_Public_Data.Port{Name: name, SID: "66->69", BlockType: "Inport", PortType: "S-R", Kind: Public_data.M1PortKindData, Virtual: true})
+		}
+	}
+	return []*M1_Public_Data.Block{top, a, b}
+}
+
+func TestComputeM1ForNodesVirtualPorts(t *testing.T) {
+	rules := Public_data.DefaultM1LevelRules
+
+	coverage := func(withVirtual bool) float64 {
+		nodes := nodesFromBlocks(virtualPortBlocks(withVirtual))
+		computeM1ForNodes(nodes, rules)
+		return nodes[0].Coverage
+	}
+
+	// 가상 포트가 없으면 하위 노드의 포트 합계가 0이므로 coverage.m1도 0입니다.
+	if got := coverage(false); got != 0 {
+		t.Errorf("가상 포트 없음: coverage = %v, want 0", got)
+	}
+	// 가상 포트가 있으면 1(Top 포트) × 2(하위 노드 수) × 4(하위 노드 포트 합계) = 8
+	if got := coverage(true); got != 8 {
+		t.Errorf("가상 포트 있음: coverage = %v, want 8", got)
+	}
+}
+
+func TestCountLintForNodesRootFindingsOnce(t *testing.T) {
+	blocks := []*M1_Public_Data.Block{
+		{Level: 1, Name: "B", SID: "2", BlockType: "SubSystem", Path: "2"},
+		{Level: 1, Name: "A", SID: "1", BlockType: "SubSystem", Path: "1"},
+	}
+	systems := []M1_Public_Data.LintSystem{
+		{File: "M/system_root.xml", Level: 1, Findings: []M1_Public_Data.LintFinding{{Kind: Model_Lint.DanglingLine, SID: "9", Port: "out:1"}}},
+		{File: "M/system_2.xml", Level: 2, Father: "B", FatherPath: "2", Findings: []M1_Public_Data.LintFinding{{Kind: Model_Lint.Placeholder, SID: "21"}}},
+	}
+	nodes := nodesFromBlocks(blocks)
+	countLintForNodes(nodes, systems)
+
+	got := make(map[string]int)
+	total := 0
+	for _, n := range nodes {
+		got[n.Name] = n.Dangling
+		total += n.Dangling
+	}
+	// system_root의 결함은 LDI에 먼저 작성되는 L1 노드(A)에만 한 번 셉니다.
+	if got["A"] != 1 || got["B"] != 1 {
+		t.Errorf("Dangling = %v, want A=1 B=1", got)
+	}
+	if want := Model_Lint.Total([]*M1_Public_Data.ModelResult{{Lint: systems}}); total != want {
+		t.Errorf("노드별 결함 수 합계 = %d, want Model_Lint.Total = %d", total, want)
+	}
+}
+
+// zipEntry는 테스트용 압축 파일의 항목 하나입니다.
+type zipEntry struct {
+	name string
+	mode os.FileMode // 0이면 일반 파일
+	data string
+}
+
+// writeZip은 entries로 압축 파일을 만들어 path에 저장합니다.
+func writeZip(t *testing.T, path string, entries []zipEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			h.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnzipOneRejectsUnsafeEntries(t *testing.T) {
+	limits := M1_Public_Data.ExtractLimits{MaxEntries: 10, MaxEntryBytes: 64, MaxTotalBytes: 1 << 10}
+	base := t.TempDir()
+	absTarget := filepath.Join(base, "abs.xml")
+
+	tests := []struct {
+		name  string
+		entry zipEntry
+	}{
+		{"상위 경로", zipEntry{name: "../x.xml", data: "x"}},
+		{"절대 경로", zipEntry{name: absTarget, data: "x"}},
+		{"드라이브 경로", zipEntry{name: `C:\x.xml`, data: "x"}},
+		{"역슬래시 상위 경로", zipEntry{name: `a\..\..\x.xml`, data: "x"}},
+		{"심볼릭 링크", zipEntry{name: "simulink/link", mode: os.ModeSymlink | 0777, data: "../../x.xml"}},
+		{"크기 제한 초과", zipEntry{name: "simulink/big.xml", data: strings.Repeat("x", 100)}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// sandbox 안의 out에만 풀려야 하므로, 실패 후 sandbox에는 아무것도 남지 않아야 합니다.
+			sandbox := filepath.Join(base, "sandbox"+strconv.Itoa(i))
+			destDir := filepath.Join(sandbox, "out")
+			zipPath := filepath.Join(base, "model"+strconv.Itoa(i)+".slx")
+			writeZip(t, zipPath, []zipEntry{{name: "simulink/blockdiagram.xml", data: "<x/>"}, tt.entry})
+
+			if err := unzipOne(zipPath, destDir, limits); err == nil {
+				t.Fatalf("항목 %q의 압축 해제가 실패하지 않았습니다", tt.entry.name)
+			}
+			err := filepath.Walk(sandbox, func(p string, info os.FileInfo, err error) error {
+				if err == nil && p != sandbox {
+					t.Errorf("압축 해제 실패 후 남은 경로: %s", p)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Lstat(absTarget); !os.IsNotExist(err) {
+				t.Errorf("절대 경로 항목이 destDir 밖에 만들어졌습니다: %s", absTarget)
+			}
+		})
+	}
+}
+
+func TestUnzipOneExtractsRegularEntries(t *testing.T) {
+	base := t.TempDir()
+	zipPath := filepath.Join(base, "model.slx")
+	writeZip(t, zipPath, []zipEntry{
+		{name: "simulink/blockdiagram.xml", data: "<x/>"},
+		{name: `simulink\systems\system_root.xml`, data: "<System/>"},
+	})
+
+	destDir := filepath.Join(base, "out")
+	if err := unzipOne(zipPath, destDir, M1_Public_Data.DefaultExtractLimits); err != nil {
+		t.Fatalf("unzipOne: %v", err)
+	}
+	for _, name := range []string{"simulink/blockdiagram.xml", "simulink/systems/system_root.xml"} {
+		if _, err := os.Stat(filepath.Join(destDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s가 풀리지 않았습니다: %v", name, err)
+		}
+	}
+}
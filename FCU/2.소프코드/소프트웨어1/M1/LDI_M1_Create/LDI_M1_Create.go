@@ -1,85 +1,36 @@
 package LDI_M1_Create
 
 import (
-	"encoding/xml"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M1/M1_Public_Data"
-	"FCU_Tools/Public_data"
 )
 
-// XML 구조 정의
-type Property struct {
-	XMLName xml.Name `xml:"property"`
-	Name    string   `xml:"name,attr"`
-	Value   string   `xml:",chardata"`
-}
-
-type Uses struct {
-	XMLName  xml.Name `xml:"uses"`
-	Provider string   `xml:"provider,attr"`
-	Strength string   `xml:"strength,attr,omitempty"`
-}
-
-type Element struct {
-	XMLName  xml.Name   `xml:"element"`
-	Name     string     `xml:"name,attr"`
-	Uses     []Uses     `xml:"uses"`
-	Property []Property `xml:"property"`
-}
-
-type Root struct {
-	XMLName xml.Name  `xml:"ldi"`
-	Items   []Element `xml:"element"`
-}
-
-// MergeM1ToMainLDI
-// LDIDir 디렉터리에서 M1 단계에 생성된 모든 *.ldi.xml의 coverage.m1을
-// 주 LDI(Output/result.ldi.xml)에 병합합니다.
+// CollectM1LDI
+// ws.LDIDir 디렉터리에서 M1 단계에 생성된 모든 *.ldi.xml을 읽어 하나의 M1 조각 문서로 모읍니다.
 //
 // 설명:
-// - 가정: M1의 *.ldi.xml은 생성 단계에서 이미 “모델명 변경”(예: GenerateM1LDIFromTxt가 txt 파일명을 모델명으로 사용)을 완료했다.
+// - 가정: M1의 *.ldi.xml은 생성 단계에서 이미 “모델명 변경”(예: GenerateM1LDI가 분석 결과의 모델명을 사용)을 완료했다.
 // - 따라서 여기서는 더 이상 asw.csv를 읽지 않고, runnable→모델명 매핑도 수행하지 않으며, M1의 ldi.xml을 제자리에서 수정하지도 않는다.
-func MergeM1ToMainLDI() error {
-	// 1) 주 LDI 경로 확정
-	if Public_data.OutputDir == "" {
-		return fmt.Errorf("주 LDI 출력 디렉터리가 초기화되지 않았습니다. 먼저 InitOutputDirectory를 호출하세요.")
-	}
-	mainLDIPath := filepath.Join(Public_data.OutputDir, "result.ldi.xml")
-
-	// 2) 주 LDI 읽기
-	mainData, err := ioutil.ReadFile(mainLDIPath)
-	if err != nil {
-		return fmt.Errorf("주 LDI 파일 읽기 실패 [%s]: %v", mainLDIPath, err)
-	}
-
-	var mainRoot Root
-	if err := xml.Unmarshal(mainData, &mainRoot); err != nil {
-		return fmt.Errorf("주 LDI XML 파싱 실패: %v", err)
-	}
-
-	// 3) M1 LDI 디렉터리를 스캔하여 coverage.m1을 수집합니다(키는 el.Name을 그대로 사용).
-	m1Dir := M1_Public_Data.LDIDir
+// - 읽지 못한 파일이 있으면 나머지 파일로 만든 조각과 함께 모은 오류를 반환한다.
+func CollectM1LDI(ws *M1_Public_Data.Workspace) (*LDI_Model.Document, error) {
+	m1Dir := ws.LDIDir
 	if m1Dir == "" {
-		return fmt.Errorf("M1_Public_Data.LDIDir가 설정되지 않아 M1의 LDI 파일 디렉터리를 찾을 수 없습니다.")
+		return nil, fmt.Errorf("LDIDir가 설정되지 않아 M1의 LDI 파일 디렉터리를 찾을 수 없습니다.")
 	}
 
 	entries, err := os.ReadDir(m1Dir)
 	if err != nil {
-		return fmt.Errorf("M1 LDI 디렉터리 읽기 실패 [%s]: %v", m1Dir, err)
+		return nil, fmt.Errorf("M1 LDI 디렉터리 읽기 실패 [%s]: %v", m1Dir, err)
 	}
 
-	// element name → coverage.m1
-	m1Map := make(map[string]string)
-
-	// element name → provider → strength(int)
-	m1UsesMap := make(map[string]map[string]int)
-
+	frag := LDI_Model.New()
+	var errs []error
 	for _, e := range entries {
 		if e.IsDir() {
 			continue
@@ -89,150 +40,32 @@ func MergeM1ToMainLDI() error {
 		}
 
 		path := filepath.Join(m1Dir, e.Name())
-		data, err := ioutil.ReadFile(path)
+		m1Doc, err := LDI_Model.Load(path)
 		if err != nil {
-			fmt.Printf("⚠️ M1 LDI 파일 읽기 실패 [%s]: %v\n", path, err)
-			continue
-		}
-
-		var m1Root Root
-		if err := xml.Unmarshal(data, &m1Root); err != nil {
-			fmt.Printf("⚠️ M1 LDI 파일 파싱 실패 [%s]: %v\n", path, err)
+			fmt.Printf("⚠️ M1 LDI 파일 처리 실패: %v\n", err)
+			errs = append(errs, err)
 			continue
 		}
+		frag.Merge(m1Doc, LDI_Model.MergeOptions{CreateMissing: true})
+	}
+	return frag, errors.Join(errs...)
+}
 
-		for _, el := range m1Root.Items {
-
-			for _, p := range el.Property {
-				if p.Name == "coverage.m1" {
-					m1Map[el.Name] = p.Value
-				}
-			}
-
-			if len(el.Uses) > 0 {
-				if m1UsesMap[el.Name] == nil {
-					m1UsesMap[el.Name] = make(map[string]int)
-				}
-				for _, u := range el.Uses {
-					prov := strings.TrimSpace(u.Provider)
-					if prov == "" {
-						continue
-					}
-					m1UsesMap[el.Name][prov] += parseStrengthToInt(u.Strength)
-				}
-			}
-		}
+// MergeM1ToMainLDI
+// M1 조각 문서의 coverage.m1과 uses를 주 LDI에 병합합니다.
+// 주 LDI에 없는 요소(하위 계층 노드)는 새로 추가하고, 같은 provider의 uses는 strength를 누적합니다.
+func MergeM1ToMainLDI(mainLDI, m1Doc *LDI_Model.Document) error {
+	if mainLDI == nil {
+		return fmt.Errorf("주 LDI가 생성되지 않았습니다. 먼저 의존 관계 분석을 수행하세요.")
 	}
 
-	if len(m1Map) == 0 && len(m1UsesMap) == 0 {
+	if m1Doc == nil || len(m1Doc.Items) == 0 {
 		fmt.Println("ℹ️ M1 LDI 디렉터리에서 coverage.m1 속성을 하나도 찾지 못해 주 LDI를 수정하지 않습니다.")
 		return nil
 	}
 
-	// 4) 주 LDI의 인덱스 테이블을 구축합니다: element name → index
-	mainIndex := make(map[string]int)
-	for i, el := range mainRoot.Items {
-		mainIndex[el.Name] = i
-	}
-
-	// 5) coverage.m1을 주 LDI에 병합합니다.
-	for name, val := range m1Map {
-		if idx, ok := mainIndex[name]; ok {
-			el := &mainRoot.Items[idx]
-			exists := false
-			for _, p := range el.Property {
-				if p.Name == "coverage.m1" {
-					exists = true
-					break
-				}
-			}
-			if !exists {
-				el.Property = append(el.Property, Property{Name: "coverage.m1", Value: val})
-			}
-		} else {
-			newEl := Element{
-				Name: name,
-				Property: []Property{
-					{Name: "coverage.m1", Value: val},
-				},
-			}
-			mainRoot.Items = append(mainRoot.Items, newEl)
-			mainIndex[name] = len(mainRoot.Items) - 1
-		}
-	}
-
-	for name, provMap := range m1UsesMap {
-		if len(provMap) == 0 {
-			continue
-		}
-
-		idx, ok := mainIndex[name]
-		if !ok {
-			newEl := Element{Name: name}
-			mainRoot.Items = append(mainRoot.Items, newEl)
-			mainIndex[name] = len(mainRoot.Items) - 1
-			idx = mainIndex[name]
-		}
-
-		el := &mainRoot.Items[idx]
-
-		existing := make(map[string]int)
-		for i, u := range el.Uses {
-			prov := strings.TrimSpace(u.Provider)
-			if prov == "" {
-				continue
-			}
-			existing[prov] = i
-		}
-
-		for prov, addStrength := range provMap {
-			prov = strings.TrimSpace(prov)
-			if prov == "" || addStrength <= 0 {
-				continue
-			}
-
-			if pos, ok := existing[prov]; ok {
-				// 이미 존재함: strength를 누적
-				cur := parseStrengthToInt(el.Uses[pos].Strength)
-				sum := cur + addStrength
-				el.Uses[pos].Strength = strconv.Itoa(sum)
-			} else {
-				// 존재하지 않음: uses를 하나 추가
-				el.Uses = append(el.Uses, Uses{
-					Provider: prov,
-					Strength: strconv.Itoa(addStrength),
-				})
-				existing[prov] = len(el.Uses) - 1
-			}
-		}
-	}
-
-	// 6) 주 LDI에 다시 씁니다.
-	out, err := xml.MarshalIndent(mainRoot, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("주 LDI XML 직렬화 실패: %v", err)
-	}
-
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(mainLDIPath, append(header, out...), 0644); err != nil {
-		return fmt.Errorf("주 LDI 파일 쓰기 실패 [%s]: %v", mainLDIPath, err)
-	}
+	mainLDI.Merge(m1Doc, LDI_Model.MergeOptions{CreateMissing: true})
 
 	fmt.Println("✅ M1지표 병합 성공")
 	return nil
 }
-
-func parseStrengthToInt(s string) int {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return 0
-	}
-	v, err := strconv.Atoi(s)
-	if err != nil {
-		return 0
-	}
-	if v < 0 {
-		return 0
-	}
-	return v
-}
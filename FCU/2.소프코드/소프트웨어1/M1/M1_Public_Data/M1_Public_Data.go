@@ -1,61 +1,231 @@
 package M1_Public_Data
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-var (
-	WorkDir   string	//코드가 실행되는 위치로, 실제 M1의 위치가 아닙니다.
-	M1Dir     string	//M1의 위치
-	BuildDir  string	//M1 하위의 Build 폴더 위치
-	OutputDir string	//M1 폴더 내 output 폴더의 위치
-	LDIDir    string	//M1의 output 폴더 내 LDI 폴더 위치
-	TxtDir    string	//M1의 output 폴더 내 txt 폴더 위치
+// Workspace는 한 번의 M1 실행에서 사용하는 작업 경로를 담습니다.
+// 전역 변수 대신 이 값을 각 단계에 전달하므로, 같은 프로세스에서 여러 번(또는 동시에) 실행할 수 있습니다.
+type Workspace struct {
+	M1Dir     string //M1의 위치
+	BuildDir  string //M1 하위의 Build 폴더 위치
+	OutputDir string //M1 폴더 내 output 폴더의 위치
+	LDIDir    string //M1의 output 폴더 내 LDI 폴더 위치
+	TxtDir    string //M1의 output 폴더 내 txt 폴더 위치
 
-	SrcPath   string	//여기에는 사용자가 입력한 Windows 경로(모델 경로)를 저장합니다.
-)
+	SrcPath string //여기에는 사용자가 입력한 Windows 경로(모델 경로)를 저장합니다.
+
+	Limits ExtractLimits //slx 압축 해제 시 적용하는 제한
+
+	// ExtractToDisk가 true이면 기존 방식대로 slx를 BuildDir에 복사하고 압축을 풀어 분석합니다(디버그용).
+	// false(기본)이면 slx를 메모리에서 바로 읽으며 BuildDir를 만들지 않습니다.
+	ExtractToDisk bool
+
+	// DumpTxt/DumpJSON이 true이면 분석한 블록 트리를 TxtDir 하위에 사람이 읽을 수 있는 txt / JSON으로도 출력합니다.
+	// 분석 자체는 메모리의 블록 트리만 사용하므로, 둘 다 false(기본)이면 TxtDir를 만들지 않습니다.
+	DumpTxt  bool
+	DumpJSON bool
+}
+
+// ExtractLimits는 slx(zip) 압축 해제 시 적용하는 제한입니다. 공급사에서 받은 모델을 분석하므로
+// 비정상적으로 크거나 항목이 많은 압축 파일이 디스크를 가득 채우지 않도록 막습니다. 0이면 제한하지 않습니다.
+type ExtractLimits struct {
+	MaxEntries    int   //압축 파일 하나의 최대 항목 수
+	MaxEntryBytes int64 //항목 하나의 최대 압축 해제 크기(바이트)
+	MaxTotalBytes int64 //압축 파일 하나의 전체 압축 해제 크기(바이트)
+}
+
+// DefaultExtractLimits는 일반적인 Simulink 모델보다 충분히 큰 기본 제한입니다.
+var DefaultExtractLimits = ExtractLimits{
+	MaxEntries:    10000,
+	MaxEntryBytes: 512 << 20, // 512MB
+	MaxTotalBytes: 2 << 30,   // 2GB
+}
+
+// ModelResult는 모델 하나의 분석 결과입니다.
+// 모델마다 별도의 결과를 만들고 각 워커는 자기 모델의 결과에만 쓰므로, 여러 모델을 잠금 없이 병렬로 분석할 수 있습니다.
+type ModelResult struct {
+	Name   string
+	Blocks []*Block     //분석한 블록(계층 노드)을 분석 순서대로 담습니다. 레벨과 부모 이름으로 트리를 이룹니다.
+	Log    bytes.Buffer //콘솔 메시지(병렬로 분석해도 모델 순서대로 출력하기 위해 모아 둡니다)
+	Err    error        //분석 실패 시 오류(Blocks에는 실패 전까지의 결과가 남아 있습니다)
+
+	CSPorts []CSPort //모델의 C-S 포트(L1 분석 시 읽어 두고 L2 이후 노드에도 반영합니다)
+
+	Lint []LintSystem //분석한 system 파일별 연결 결함(Model_Lint), 분석 순서대로 담습니다
+}
+
+// Block은 M1 계층의 노드 하나(L1: SubSystem, L2: SubSystem, L3+: 포트 블록이 아닌 Block)입니다.
+type Block struct {
+	Level      int       `json:"level"`
+	Name       string    `json:"name"`
+	SID        string    `json:"sid"`
+	BlockType  string    `json:"block_type"`
+	Path       string    `json:"path"`                  //system_root부터 이 블록까지의 SID 경로(예: "1/8/12"), 노드 식별에 사용
+	Father     string    `json:"father,omitempty"`      //부모 노드 이름(L1은 빈 문자열), 표시용
+	FatherPath string    `json:"father_path,omitempty"` //부모 노드의 SID 경로(L1은 빈 문자열)
+	Ports      []Port    `json:"ports,omitempty"`       //이 블록에 연결된 포트
+	Connects   []Connect `json:"connects,omitempty"`    //L2+ SubSystem에서 다른 SubSystem으로 향하는 연결
+
+	Masked         bool   `json:"masked,omitempty"`          //마스크가 적용된 블록
+	Atomic         bool   `json:"atomic,omitempty"`          //원자 단위로 실행되는 SubSystem(TreatAsAtomicUnit=on)
+	LibraryLink    string `json:"library_link,omitempty"`    //라이브러리 링크(Reference)의 SourceBlock(예: "MyLib/Filter")
+	ModelReference string `json:"model_reference,omitempty"` //모델 참조(ModelReference)가 가리키는 모델 이름
+}
+
+// Port는 블록에 연결된 포트 하나입니다.
+type Port struct {
+	Name      string `json:"name"`
+	SID       string `json:"sid"`
+	BlockType string `json:"block_type"`     //Inport / Outport
+	PortType  string `json:"port_type"`      //S-R / C-S
+	Kind      string `json:"kind,omitempty"` //S-R: 포트 종류(Public_data.M1PortKinds: data, bus_element, trigger, ...)
+	Virtual   bool   `json:"virtual,omitempty"`
+
+	Arguments []string `json:"arguments,omitempty"` //C-S: 연산의 입력 인자
+	Outputs   []string `json:"outputs,omitempty"`   //C-S: 연산의 출력 인자
+}
+
+// CSPort는 모델의 C-S 포트(graphicalInterface의 RequireFunction/ProvideFunction) 하나입니다.
+type CSPort struct {
+	Name      string    `json:"name"`
+	BlockType string    `json:"block_type"` //Require → Inport, Provide → Outport
+	Prototype string    `json:"prototype,omitempty"`
+	Arguments []string  `json:"arguments,omitempty"`
+	Outputs   []string  `json:"outputs,omitempty"`
+	Blocks    []CSBlock `json:"blocks,omitempty"` //이 포트를 구현한 Function Caller / Simulink Function 블록(찾지 못하면 비어 있음)
+}
+
+// CSBlock은 C-S 포트를 구현한 블록(Require: Function Caller, Provide: Simulink Function)입니다.
+type CSBlock struct {
+	SID       string `json:"sid"`
+	Path      string `json:"path"`      //system_root부터 이 블록까지의 SID 경로
+	Subsystem string `json:"subsystem"` //블록을 포함하는 SubSystem 이름(최상위이면 빈 문자열)
+}
+
+// CSUnknownSID는 구현 블록을 찾지 못한 C-S 포트의 SID입니다.
+const CSUnknownSID = "unknow"
+
+// Port는 C-S 포트를 블록의 포트로 변환합니다. 구현 블록이 있으면 첫 번째 블록의 SID를 사용합니다.
+func (p CSPort) Port() Port {
+	sid := CSUnknownSID
+	if len(p.Blocks) > 0 {
+		sid = p.Blocks[0].SID
+	}
+	return Port{Name: p.Name, SID: sid, BlockType: p.BlockType, PortType: "C-S", Arguments: p.Arguments, Outputs: p.Outputs}
+}
+
+// ImplementedUnder는 path 블록(또는 그 하위)에 이 포트의 구현 블록이 있으면 그 블록을 반환합니다.
+func (p CSPort) ImplementedUnder(path string) (CSBlock, bool) {
+	for _, b := range p.Blocks {
+		if b.Path == path || strings.HasPrefix(b.Path, path+SIDPathSeparator) {
+			return b, true
+		}
+	}
+	return CSBlock{}, false
+}
+
+// LintSystem은 system 파일 하나(부모 노드의 내용)에서 찾은 연결 결함입니다.
+type LintSystem struct {
+	File       string        `json:"file"`        //"모델/system_xxx.xml"(라이브러리 링크/참조 모델이면 해당 모델)
+	Level      int           `json:"level"`       //이 system 안의 블록이 속하는 레벨(system_root는 1)
+	Father     string        `json:"father"`      //부모 노드 이름(system_root는 빈 문자열)
+	FatherPath string        `json:"father_path"` //부모 노드의 SID 경로(system_root는 빈 문자열)
+	Findings   []LintFinding `json:"findings,omitempty"`
+}
+
+// LintFinding은 연결 결함 하나(연결되지 않은 포트, Ground/Terminator, 끝이 없는 선 등)입니다.
+type LintFinding struct {
+	Kind      string `json:"kind"`
+	SID       string `json:"sid,omitempty"`        //결함이 있는 블록(선의 결함이면 Src 블록)의 SID
+	Block     string `json:"block,omitempty"`      //블록 이름
+	BlockType string `json:"block_type,omitempty"` //블록의 BlockType
+	Port      string `json:"port,omitempty"`       //포트("in:2", "trigger" 등)
+	Dst       string `json:"dst,omitempty"`        //Src가 없는 선의 입력 포트("69#in:1, 70#trigger")
+	Line      string `json:"line,omitempty"`       //선 이름
+}
+
+// Connect는 SubSystem에서 같은 레벨의 다른 SubSystem으로 향하는 연결입니다.
+type Connect struct {
+	Name     string `json:"name"` //대상 SubSystem 이름
+	SID      string `json:"sid"`
+	Strength int    `json:"strength"`
+}
+
+// Logf는 콘솔 메시지를 결과에 모아 둡니다.
+func (r *ModelResult) Logf(format string, args ...interface{}) {
+	fmt.Fprintf(&r.Log, format, args...)
+}
+
+// NewWorkspace는 m1Dir 아래의 작업 경로를 조합합니다. 디렉터리는 Init에서 생성합니다.
+func NewWorkspace(m1Dir, srcPath string) *Workspace {
+	outputDir := filepath.Join(m1Dir, "output")
+	return &Workspace{
+		M1Dir:     m1Dir,
+		BuildDir:  filepath.Join(m1Dir, "build"),
+		OutputDir: outputDir,
+		LDIDir:    filepath.Join(outputDir, "LDI"),
+		TxtDir:    filepath.Join(outputDir, "txt"),
+		SrcPath:   srcPath,
+		Limits:    DefaultExtractLimits,
+	}
+}
+
+// 작업 공간 설정
+func (w *Workspace) Init() error {
+	if w.M1Dir == "" {
+		return fmt.Errorf("M1 작업 디렉터리가 비어 있습니다. NewWorkspace()로 작업 공간을 생성하세요.")
+	}
 
-//작업 공간 설정
-func SetWorkDir() {
-	wd, err := os.Getwd()
-	if err != nil {
-		fmt.Println("❌ 현재 작업 디렉터리를 가져오는 데 실패했습니다.", err)
-		return
-	}
-	WorkDir = wd
-
-	M1Dir = filepath.Join(WorkDir, "M1")
-	BuildDir = filepath.Join(M1Dir, "build")
-	OutputDir = filepath.Join(M1Dir, "output")
-	LDIDir = filepath.Join(OutputDir, "LDI")
-	TxtDir = filepath.Join(OutputDir, "txt")
-	
 	//이전 프로젝트에서 남아 있는 파일을 삭제합니다.
-	removeIfExists(BuildDir)
-	removeIfExists(OutputDir)
+	removeIfExists(w.BuildDir)
+	removeIfExists(w.OutputDir)
 
-	//새 폴더를 생성합니다.
-	dirs := []string{M1Dir, BuildDir, LDIDir, TxtDir}
+	//새 폴더를 생성합니다. BuildDir는 디스크에 압축을 풀 때만, TxtDir는 덤프를 출력할 때만 필요합니다.
+	dirs := []string{w.M1Dir, w.LDIDir}
+	if w.DumpTxt || w.DumpJSON {
+		dirs = append(dirs, w.TxtDir)
+	}
+	if w.ExtractToDisk {
+		dirs = append(dirs, w.BuildDir)
+	}
 	for _, d := range dirs {
 		if err := os.MkdirAll(d, 0755); err != nil {
-			fmt.Printf("❌ 디렉터리 생성 실패 [%s]: %v\n", d, err)
-			return
+			return fmt.Errorf("디렉터리 생성 실패 [%s]: %v", d, err)
 		}
 	}
-
-	//fmt.Println("✅ M1 작업 공간 초기화 성공")
-	// fmt.Println("    WorkDir  :", WorkDir)
-	// fmt.Println("    M1Dir    :", M1Dir)
-	// fmt.Println("    BuildDir :", BuildDir)
-	// fmt.Println("    OutputDir:", OutputDir)
-	// fmt.Println("    LDIDir   :", LDIDir)
-	// fmt.Println("    TxtDir   :", TxtDir)
+	return nil
 }
-//해당 경로에 이 폴더가 존재하면 삭제(정리)합니다.
+
+// 해당 경로에 이 폴더가 존재하면 삭제(정리)합니다.
 func removeIfExists(path string) {
 	if _, err := os.Stat(path); err == nil {
 		_ = os.RemoveAll(path)
 	}
 }
+
+// SIDPathSeparator는 SID 경로의 구분자입니다.
+const SIDPathSeparator = "/"
+
+// JoinSIDPath는 부모 노드의 SID 경로 뒤에 sid를 이어 붙입니다. fatherPath가 비어 있으면(L1) sid만 반환합니다.
+// 이름은 서로 다른 부모 아래에서 중복될 수 있으므로, 노드는 이름 대신 이 경로로 식별합니다.
+func JoinSIDPath(fatherPath, sid string) string {
+	if fatherPath == "" {
+		return sid
+	}
+	return fatherPath + SIDPathSeparator + sid
+}
+
+// IsSubSystemLike는 하위 계층을 가지는 블록(SubSystem, 라이브러리 링크, 모델 참조)인지 반환합니다.
+// 라이브러리 링크(Reference)와 모델 참조(ModelReference)는 연결 분석에서 SubSystem과 같이 취급합니다.
+func IsSubSystemLike(blockType string) bool {
+	switch blockType {
+	case "SubSystem", "Reference", "ModelReference":
+		return true
+	}
+	return false
+}
@@ -1,32 +1,175 @@
 package M1main
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M1/Analysis_Process"
 	"FCU_Tools/M1/File_Utils_M1"
 	"FCU_Tools/M1/LDI_M1_Create"
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Consistency"
+	"FCU_Tools/M1/Model_Discovery"
+	"FCU_Tools/M1/Model_Lint"
+	"FCU_Tools/M1/Model_Reader"
+	"FCU_Tools/Metric_Registry"
+	"FCU_Tools/Public_data"
+	"FCU_Tools/SWC_Dependence"
 )
 
-func M1_main() {
-	// 1. 작업 공간 생성: M1/Build, M1/Output/LDI, M1/Output/txt
-	M1_Public_Data.SetWorkDir()
+// Metric은 M1 지표를 Metric_Registry에 등록하기 위한 구현입니다.
+type Metric struct{}
+
+func init() {
+	Metric_Registry.Register(Metric{})
+}
+
+func (Metric) Name() string       { return "M1" }
+func (Metric) Requires() []string { return []string{"SWC"} }
+
+// Compute는 M1 단계를 순서대로 실행합니다.
+// 작업 공간이나 입력 경로를 준비하지 못하면 즉시 실패하고, 그 밖의 단계 오류는 모아 두었다가
+// 성공한 모델의 조각과 함께 반환합니다(부분 실패).
+func (Metric) Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	// 1. 작업 공간 생성: <WorkDir>/M1/output/LDI (덤프 출력 시 M1/output/txt, 디스크 압축 해제 모드에서는 M1/build도 생성)
+	ws := M1_Public_Data.NewWorkspace(cfg.MetricWorkDir("M1"), cfg.ModelDir)
+	ws.ExtractToDisk = cfg.M1ExtractToDisk
+	ws.DumpTxt = cfg.M1DumpTxt
+	ws.DumpJSON = cfg.M1DumpJSON
+	if err := ws.Init(); err != nil {
+		return nil, err
+	}
+
+	// 2. 모델의 Windows 경로 읽기(cfg.ModelDir가 비어 있을 때만 입력을 받습니다)
+	if err := File_Utils_M1.ReadWindowsPath(ws); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	// 3. 설정의 m1.discovery 규칙(기본: <모델 디렉터리>/<Name>/<Name>.slx 또는 .mdl)으로 분석할 모델을 찾습니다.
+	// 찾은 모델과 건너뛴 파일, 그 이유는 M1/output/model_discovery.txt에 기록합니다.
+	found, err := Model_Discovery.Discover(ws.SrcPath, cfg.M1Discovery)
+	if err != nil {
+		return nil, err
+	}
+	reportPath := filepath.Join(ws.OutputDir, "model_discovery.txt")
+	if err := found.WriteReport(reportPath); err != nil {
+		errs = append(errs, err)
+	}
+	fmt.Printf("🔎 모델 검색: %d개 모델을 찾았고 %d개 파일을 건너뛰었습니다 (보고서: %s)\n", len(found.Found), len(found.Skipped), reportPath)
+	for _, sk := range found.Skipped {
+		fmt.Printf("  ⏭️ %s: %s\n", sk.Rel, sk.Reason)
+	}
+	if len(found.Found) == 0 {
+		fmt.Printf("⚠️ 분석할 모델이 없습니다 [%s]\n", ws.SrcPath)
+	}
 
-	// 2. 모델의 Windows 경로 읽기
-	File_Utils_M1.ReadWindowsPath()
+	// asw.csv의 컴포넌트와 찾은 모델의 SWC 이름을 비교하여 M1/output/model_consistency.txt에 기록합니다.
+	consistency, err := checkConsistency(cfg, ws, found.Found)
+	if err != nil {
+		errs = append(errs, err)
+	}
 
-	// 3. 요구 조건을 만족하는 slx 파일을 BuildDir로 복사합니다.
-	File_Utils_M1.CopySlxToBuild()
+	// 4. 찾은 모델을 SWC 이름으로 엽니다. 기본은 메모리에서 바로 읽으며,
+	// 설정의 m1.extract_to_disk가 true이면 BuildDir에 복사하고 압축을 풀어 읽습니다(디버그용).
+	models, err := File_Utils_M1.OpenModels(ws, found.Found)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	defer File_Utils_M1.CloseModels(models)
 
-	// 4. slx 파일을 BuildDir 아래의 동일한 이름의 디렉터리로 압축 해제합니다.
-	File_Utils_M1.UnzipSlxFiles()
+	// 라이브러리 링크와 참조 모델은 모델 디렉터리(하위 폴더 포함)의 .slx/.mdl/.slxp에서 찾습니다.
+	// 찾을 수 없으면 해당 블록을 말단 블록으로 처리합니다.
+	resolver, err := Model_Reader.NewResolver(ws.SrcPath, ws.Limits)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	defer resolver.Close()
 
-	// 5. 분석 흐름을 설정하며, 파라미터에 따라 분석 깊이가 결정됩니다.
-	// 다만 현재 요구사항이 3단계(3층)까지이므로, 테스트는 3단계까지만 수행했습니다.
-	Analysis_Process.RunAnalysis(3)
+	// 5. 분석 흐름을 설정하며, 설정의 m1.max_depth(기본 3, 0이면 무제한)에 따라 분석 깊이가 결정됩니다.
+	// 레벨별로 노드로 셀 BlockType과 포트 가중치는 설정의 m1.levels(기본: Public_data.DefaultM1LevelRules)를 따릅니다.
+	// 모델은 설정의 m1.workers개(0이면 CPU 수)의 워커가 병렬로 분석합니다.
+	workers := cfg.M1Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	// 분석 결과는 모델별 블록/포트/연결 트리로 메모리에 남습니다.
+	rules := cfg.ResolvedM1LevelRules()
+	results, err := Analysis_Process.RunAnalysis(models, resolver, rules, cfg.M1MaxDepth, workers)
+	if err != nil {
+		errs = append(errs, err)
+	}
 
-	// 6. txt 파일을 기반으로 ldi.xml 파일을 생성합니다.
-	File_Utils_M1.GenerateM1LDIFromTxt()
+	// 분석한 system 파일에서 찾은 연결 결함(연결되지 않은 포트, Ground/Terminator, 끝이 없는 선)을
+	// SubSystem별로 M1/output/model_lint.txt에 기록합니다. 요소별 개수는 LDI의 m1.dangling 속성으로 기록됩니다.
+	lintPath := filepath.Join(ws.OutputDir, "model_lint.txt")
+	if err := Model_Lint.WriteReport(lintPath, results); err != nil {
+		errs = append(errs, err)
+	}
+	fmt.Printf("🧹 모델 점검: 연결 결함 %d개 (보고서: %s)\n", Model_Lint.Total(results), lintPath)
+
+	// 6. 블록 트리를 기반으로 ldi.xml 파일을 생성합니다(설정에 따라 txt/JSON 덤프도 출력합니다).
+	if err := File_Utils_M1.GenerateM1LDI(ws, results, rules); err != nil {
+		errs = append(errs, err)
+	}
+
+	// 7. 모델별 M1 ldi.xml을 하나의 조각 문서로 모읍니다.
+	frag, err := LDI_M1_Create.CollectM1LDI(ws)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// 설정의 m1.consistency_properties가 true이면 불일치를 m1.consistency 속성으로 조각에 기록합니다.
+	if cfg.M1ConsistencyProperties && consistency != nil {
+		Model_Consistency.Annotate(frag, *consistency)
+	}
+	return frag, errors.Join(errs...)
+}
+
+// checkConsistency는 asw.csv의 컴포넌트 목록과 찾은 모델 목록을 비교하고, 불일치를 출력하고 보고서로 저장합니다.
+// asw.csv를 읽지 못하면 비교하지 않고 오류를 반환합니다.
+func checkConsistency(cfg *Public_data.RunConfig, ws *M1_Public_Data.Workspace, found []Model_Discovery.Entry) (*Model_Consistency.Report, error) {
+	rows, err := SWC_Dependence.LoadASWRows(cfg.ConnectorFilePath, cfg.ASWColumns)
+	if err != nil {
+		return nil, fmt.Errorf("asw.csv 컴포넌트와 모델 비교 실패: %v", err)
+	}
+	components := make([]string, 0, len(rows))
+	for _, row := range rows {
+		components = append(components, row.Component)
+	}
+	models := make([]string, 0, len(found))
+	for _, e := range found {
+		models = append(models, e.SWC)
+	}
+
+	rep := Model_Consistency.Check(components, models)
+	reportPath := filepath.Join(ws.OutputDir, "model_consistency.txt")
+	if err := rep.WriteReport(reportPath); err != nil {
+		return &rep, err
+	}
+	if rep.OK() {
+		fmt.Printf("✅ asw.csv 컴포넌트와 모델이 모두 일치합니다 (%d개)\n", rep.Matched)
+		return &rep, nil
+	}
+	fmt.Printf("⚠️ asw.csv 컴포넌트와 모델 불일치: 모델 없음 %d개, asw.csv에 없음 %d개, 대소문자 다름 %d개 (보고서: %s)\n",
+		len(rep.MissingModels), len(rep.OrphanModels), len(rep.CaseMismatches), reportPath)
+	for _, c := range rep.MissingModels {
+		fmt.Printf("  ❌ 모델 없음: %s\n", c)
+	}
+	for _, m := range rep.OrphanModels {
+		fmt.Printf("  ❓ asw.csv에 없음: %s\n", m)
+	}
+	for _, mm := range rep.CaseMismatches {
+		fmt.Printf("  🔠 대소문자 다름: 컴포넌트 %s ↔ 모델 %s\n", mm.Component, mm.Model)
+	}
+	return &rep, nil
+}
 
-	// 7. M1의 ldi.xml을 주(메인) ldi.xml에 병합합니다.
-	LDI_M1_Create.MergeM1ToMainLDI()
+// Merge: M1 조각의 하위 계층 요소는 주 LDI에 없으므로 새로 추가합니다.
+func (Metric) Merge(mainLDI, frag *LDI_Model.Document) error {
+	return LDI_M1_Create.MergeM1ToMainLDI(mainLDI, frag)
 }
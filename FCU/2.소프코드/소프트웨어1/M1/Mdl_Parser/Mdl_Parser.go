@@ -0,0 +1,198 @@
+// Mdl_Parser.go
+package Mdl_Parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// .mdl(텍스트 형식 Simulink 모델)은 아래와 같이 "이름 {" … "}" 구간과 "키 값" 파라미터로 이루어져 있습니다.
+//
+//	Model {
+//	  Name "TurnLight"
+//	  System {
+//	    Name "TurnLight"
+//	    Block {
+//	      BlockType SubSystem
+//	      Name "Ctrl"
+//	      SID "4"
+//	      System { ... }
+//	    }
+//	    Line {
+//	      SrcBlock "In1"
+//	      SrcPort 1
+//	      DstBlock "Ctrl"
+//	      DstPort 1
+//	    }
+//	  }
+//	}
+//
+// 문자열 값은 큰따옴표로 감싸며, 다음 줄이 큰따옴표로 시작하면 앞 값에 이어 붙입니다.
+
+// Param은 구간 안의 "키 값" 한 줄입니다(문자열 값은 따옴표와 이스케이프를 해제한 값).
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Section은 "이름 { … }" 구간 하나입니다.
+type Section struct {
+	Type     string
+	Params   []Param
+	Children []*Section
+	Line     int // 구간이 시작되는 줄 번호(오류 메시지용)
+}
+
+// Param은 키에 해당하는 첫 번째 파라미터 값을 반환합니다.
+func (s *Section) Param(key string) (string, bool) {
+	for _, p := range s.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Value는 키에 해당하는 파라미터 값을 반환합니다(없으면 빈 문자열).
+func (s *Section) Value(key string) string {
+	v, _ := s.Param(key)
+	return v
+}
+
+// Child는 종류가 typ인 첫 번째 하위 구간을 반환합니다.
+func (s *Section) Child(typ string) *Section {
+	for _, c := range s.Children {
+		if c.Type == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+// ChildrenOf는 종류가 typ인 모든 하위 구간을 반환합니다.
+func (s *Section) ChildrenOf(typ string) []*Section {
+	var list []*Section
+	for _, c := range s.Children {
+		if c.Type == typ {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// opcMarker 이후는 최신 .mdl에 덧붙는 OPC 패키지(XML 부분)이므로 구간 파싱 대상이 아닙니다.
+const opcMarker = "__MWOPC_PACKAGE_BEGIN__"
+
+// Parse는 .mdl 파일 내용을 최상위 구간 목록(Model 또는 Library, Stateflow 등)으로 파싱합니다.
+func Parse(data []byte) ([]*Section, error) {
+	root := &Section{}
+	stack := []*Section{root}
+	var last *Param // 직전에 읽은 문자열 파라미터(다음 줄의 이어 붙이기용)
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 64<<20)
+
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+
+		if strings.HasPrefix(line, opcMarker) {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cur := stack[len(stack)-1]
+
+		// 큰따옴표로 시작하는 줄은 직전 문자열 값의 연속입니다.
+		if strings.HasPrefix(line, `"`) {
+			s, err := unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("%d번째 줄: %v", lineNo, err)
+			}
+			if last != nil {
+				last.Value += s
+			}
+			continue
+		}
+		last = nil
+
+		if line == "}" {
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("%d번째 줄: 짝이 맞지 않는 '}'", lineNo)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		key, rest := splitKey(line)
+		if rest == "{" {
+			sec := &Section{Type: key, Line: lineNo}
+			cur.Children = append(cur.Children, sec)
+			stack = append(stack, sec)
+			continue
+		}
+
+		value := rest
+		if strings.HasPrefix(rest, `"`) {
+			s, err := unquote(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%d번째 줄: %v", lineNo, err)
+			}
+			value = s
+		}
+		cur.Params = append(cur.Params, Param{Key: key, Value: value})
+		if strings.HasPrefix(rest, `"`) {
+			last = &cur.Params[len(cur.Params)-1]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("mdl 읽기 실패: %v", err)
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("%d번째 줄에서 시작한 %s 구간이 닫히지 않았습니다", stack[len(stack)-1].Line, stack[len(stack)-1].Type)
+	}
+	return root.Children, nil
+}
+
+// splitKey는 "키 값" 줄을 키와 나머지(공백 제거)로 나눕니다.
+func splitKey(line string) (string, string) {
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], strings.TrimSpace(line[i+1:])
+}
+
+// unquote는 큰따옴표로 감싼 mdl 문자열의 따옴표와 이스케이프(\" \\ \n \t)를 해제합니다.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("닫히지 않은 문자열: %s", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,164 @@
+// Model_Consistency.go
+package Model_Consistency
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"FCU_Tools/LDI_Model"
+)
+
+// asw.csv의 컴포넌트(SWC) 목록과 M1이 찾은 모델(SWC 이름) 목록을 비교합니다.
+// 모델이 없거나 폴더 이름에 오타가 있으면 coverage.m1이 없는 컴포넌트나 asw.csv에 없는 요소가 생기므로,
+// 이를 미리 찾아 보고합니다.
+
+// LDI 속성 이름과 값(m1.consistency)입니다.
+const (
+	PropConsistency  = "m1.consistency"
+	PropASWComponent = "m1.asw_component" // 대소문자만 다른 asw.csv의 컴포넌트 이름(모델 요소에 기록)
+	PropModel        = "m1.model"         // 대소문자만 다른 모델 이름(컴포넌트 요소에 기록)
+
+	NoModel      = "no_model"      // asw.csv에는 있지만 모델이 없음
+	NoComponent  = "no_component"  // 모델은 있지만 asw.csv에 없음
+	CaseMismatch = "case_mismatch" // 이름의 대소문자만 다름
+)
+
+// Mismatch는 대소문자만 다른 컴포넌트와 모델의 쌍입니다.
+type Mismatch struct {
+	Component string
+	Model     string
+}
+
+// Report는 비교 결과입니다. 각 목록은 이름 순으로 정렬되어 있습니다.
+type Report struct {
+	Matched        int        // 이름이 정확히 같은 컴포넌트 수
+	MissingModels  []string   // 모델이 없는 컴포넌트
+	OrphanModels   []string   // asw.csv에 없는 모델
+	CaseMismatches []Mismatch // 대소문자만 다른 이름
+}
+
+// Check는 컴포넌트 이름 목록과 모델 이름 목록을 비교합니다(빈 이름과 중복은 무시합니다).
+// 정확히 같은 이름은 일치로 보고, 남은 이름 중 대소문자만 다른 쌍은 CaseMismatches로, 그 밖은 각각 모델/컴포넌트 없음으로 분류합니다.
+func Check(components, models []string) Report {
+	comps := uniqueNames(components)
+	mods := uniqueNames(models)
+
+	modelSet := make(map[string]bool, len(mods))
+	for _, m := range mods {
+		modelSet[m] = true
+	}
+	compSet := make(map[string]bool, len(comps))
+	for _, c := range comps {
+		compSet[c] = true
+	}
+
+	var rep Report
+	// 정확히 일치하지 않은 모델을 소문자 이름으로 모읍니다.
+	restModels := make(map[string][]string)
+	for _, m := range mods {
+		if !compSet[m] {
+			key := strings.ToLower(m)
+			restModels[key] = append(restModels[key], m)
+		}
+	}
+
+	usedModels := make(map[string]bool)
+	for _, c := range comps {
+		if modelSet[c] {
+			rep.Matched++
+			continue
+		}
+		key := strings.ToLower(c)
+		if cands := restModels[key]; len(cands) > 0 {
+			rep.CaseMismatches = append(rep.CaseMismatches, Mismatch{Component: c, Model: cands[0]})
+			usedModels[cands[0]] = true
+			restModels[key] = cands[1:]
+			continue
+		}
+		rep.MissingModels = append(rep.MissingModels, c)
+	}
+	for _, m := range mods {
+		if !compSet[m] && !usedModels[m] {
+			rep.OrphanModels = append(rep.OrphanModels, m)
+		}
+	}
+	return rep
+}
+
+// OK는 불일치가 하나도 없는지 반환합니다.
+func (r Report) OK() bool {
+	return len(r.MissingModels) == 0 && len(r.OrphanModels) == 0 && len(r.CaseMismatches) == 0
+}
+
+// Print는 비교 결과를 w에 출력합니다.
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "일치: %d개\n", r.Matched)
+	fmt.Fprintf(w, "모델이 없는 컴포넌트 %d개:\n", len(r.MissingModels))
+	for _, c := range r.MissingModels {
+		fmt.Fprintf(w, "  ❌ %s\n", c)
+	}
+	fmt.Fprintf(w, "asw.csv에 없는 모델 %d개:\n", len(r.OrphanModels))
+	for _, m := range r.OrphanModels {
+		fmt.Fprintf(w, "  ❓ %s\n", m)
+	}
+	fmt.Fprintf(w, "대소문자만 다른 이름 %d개:\n", len(r.CaseMismatches))
+	for _, mm := range r.CaseMismatches {
+		fmt.Fprintf(w, "  🔠 컴포넌트 %s ↔ 모델 %s\n", mm.Component, mm.Model)
+	}
+}
+
+// WriteReport는 비교 결과를 파일로 저장합니다.
+func (r Report) WriteReport(reportPath string) error {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return fmt.Errorf("모델/컴포넌트 비교 보고서 작성 실패 [%s]: %v", reportPath, err)
+	}
+	defer f.Close()
+	r.Print(f)
+	return nil
+}
+
+// Annotate는 비교 결과를 doc의 요소에 m1.consistency 속성으로 기록합니다.
+//   - 모델이 없는 컴포넌트: 컴포넌트 요소에 no_model
+//   - asw.csv에 없는 모델: 모델 요소에 no_component
+//   - 대소문자만 다른 이름: 두 요소 모두에 case_mismatch와 상대 이름(m1.model / m1.asw_component)
+//
+// 요소가 doc에 없으면 새로 추가합니다(주 LDI에 병합할 때 같은 이름의 요소에 합쳐집니다).
+func Annotate(doc *LDI_Model.Document, r Report) {
+	if doc == nil {
+		return
+	}
+	for _, c := range r.MissingModels {
+		doc.AddElement(c).SetProperty(PropConsistency, NoModel)
+	}
+	for _, m := range r.OrphanModels {
+		doc.AddElement(m).SetProperty(PropConsistency, NoComponent)
+	}
+	for _, mm := range r.CaseMismatches {
+		comp := doc.AddElement(mm.Component)
+		comp.SetProperty(PropConsistency, CaseMismatch)
+		comp.SetProperty(PropModel, mm.Model)
+
+		model := doc.AddElement(mm.Model)
+		model.SetProperty(PropConsistency, CaseMismatch)
+		model.SetProperty(PropASWComponent, mm.Component)
+	}
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,269 @@
+// Model_Discovery.go
+package Model_Discovery
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"FCU_Tools/Public_data"
+)
+
+// Entry는 분석 대상으로 찾은 모델 파일 하나입니다.
+type Entry struct {
+	Path string // 파일 경로
+	Rel  string // 모델 디렉터리 기준 상대 경로("/" 구분자)
+	SWC  string // 이 모델의 SWC 이름(LDI element 이름의 첫 구간)
+}
+
+// Skipped는 모델 디렉터리에서 찾았지만 분석하지 않는 파일과 그 이유입니다.
+type Skipped struct {
+	Rel    string
+	Reason string
+}
+
+// Result는 모델 검색 결과입니다.
+type Result struct {
+	Root    string
+	Found   []Entry
+	Skipped []Skipped
+}
+
+// 모델 파일 형식의 우선순위입니다. 같은 SWC 이름의 파일이 여러 개면 우선순위가 높은 형식을 사용합니다.
+var extRank = map[string]int{".slx": 3, ".mdl": 2, ".slxp": 1}
+
+// Discover는 root 아래에서 분석할 모델 파일을 찾습니다.
+//   - rules.Include가 비어 있으면 기존 배치(root/<Name>/<Name>.slx 또는 .mdl)만 찾습니다.
+//   - rules.Include가 있으면 하위 폴더까지 모두 검색하여, 포함 패턴 중 하나와 일치하는 파일을 찾습니다.
+//   - rules.Exclude와 일치하는 파일, 보호된 모델(.slxp), SWC 이름이 겹치는 파일은 건너뛰고 이유를 기록합니다.
+//
+// 패턴은 root 기준 상대 경로에 대한 glob이며, "**"는 0개 이상의 폴더와 일치합니다. 예: "**/*.slx", "SWC_*/model/*.mdl"
+// 심볼릭 링크는 따라가지 않습니다.
+func Discover(root string, rules Public_data.M1Discovery) (*Result, error) {
+	res := &Result{Root: root}
+
+	// 잘못된 패턴은 모든 파일과 일치하지 않는 것처럼 보이므로, 검색 전에 패턴 자체를 검사합니다.
+	for _, p := range rules.Include {
+		if err := ValidateGlob(p); err != nil {
+			return nil, fmt.Errorf("m1.discovery.include 패턴이 올바르지 않습니다 %q: %v", p, err)
+		}
+	}
+	for _, p := range rules.Exclude {
+		if err := ValidateGlob(p); err != nil {
+			return nil, fmt.Errorf("m1.discovery.exclude 패턴이 올바르지 않습니다 %q: %v", p, err)
+		}
+	}
+
+	var nameRe *regexp.Regexp
+	if rules.SWCNameRegex != "" {
+		re, err := regexp.Compile(rules.SWCNameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("m1.discovery.swc_name_regex가 올바르지 않습니다: %v", err)
+		}
+		nameRe = re
+	}
+
+	var candidates []Entry
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			if isModelFile(rel) {
+				res.Skipped = append(res.Skipped, Skipped{Rel: rel, Reason: "심볼릭 링크는 따라가지 않음"})
+			}
+			return nil
+		}
+		if d.IsDir() {
+			// 기존 배치에서는 최상위 폴더 한 단계만 봅니다.
+			if len(rules.Include) == 0 && strings.Count(rel, "/") >= 1 {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !isModelFile(rel) {
+			return nil
+		}
+
+		if pat, ok := matchAny(rules.Exclude, rel); ok {
+			res.Skipped = append(res.Skipped, Skipped{Rel: rel, Reason: fmt.Sprintf("제외 패턴 %q과 일치", pat)})
+			return nil
+		}
+		if len(rules.Include) == 0 {
+			if !isLegacyLayout(rel) {
+				res.Skipped = append(res.Skipped, Skipped{Rel: rel, Reason: "기본 배치(<이름>/<이름>.slx 또는 .mdl)가 아님 (m1.discovery.include로 지정할 수 있음)"})
+				return nil
+			}
+		} else if _, ok := matchAny(rules.Include, rel); !ok {
+			res.Skipped = append(res.Skipped, Skipped{Rel: rel, Reason: "포함 패턴과 일치하지 않음"})
+			return nil
+		}
+		if strings.EqualFold(path.Ext(rel), ".slxp") {
+			res.Skipped = append(res.Skipped, Skipped{Rel: rel, Reason: "보호된 모델(.slxp)은 내부를 분석할 수 없음"})
+			return nil
+		}
+
+		candidates = append(candidates, Entry{Path: p, Rel: rel, SWC: swcName(rel, rules.SWCNameMap, nameRe)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("모델 디렉터리 검색 실패 [%s]: %v", root, err)
+	}
+
+	// 같은 SWC 이름의 파일이 여러 개면 .slx > .mdl 순으로, 같은 형식이면 경로 순으로 먼저인 파일을 사용합니다.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].SWC != candidates[j].SWC {
+			return candidates[i].SWC < candidates[j].SWC
+		}
+		return rankOf(candidates[i].Rel) > rankOf(candidates[j].Rel)
+	})
+	used := make(map[string]string)
+	for _, c := range candidates {
+		if first, ok := used[c.SWC]; ok {
+			res.Skipped = append(res.Skipped, Skipped{Rel: c.Rel, Reason: fmt.Sprintf("SWC 이름 %q이(가) %s와 겹침", c.SWC, first)})
+			continue
+		}
+		used[c.SWC] = c.Rel
+		res.Found = append(res.Found, c)
+	}
+
+	sort.Slice(res.Skipped, func(i, j int) bool { return res.Skipped[i].Rel < res.Skipped[j].Rel })
+	return res, nil
+}
+
+// Print는 검색 결과(찾은 모델과 SWC 이름, 건너뛴 파일과 이유)를 w에 출력합니다.
+func (r *Result) Print(w io.Writer) {
+	fmt.Fprintf(w, "모델 디렉터리: %s\n", r.Root)
+	fmt.Fprintf(w, "찾은 모델 %d개:\n", len(r.Found))
+	for _, e := range r.Found {
+		fmt.Fprintf(w, "  ✅ %s → %s\n", e.Rel, e.SWC)
+	}
+	fmt.Fprintf(w, "건너뛴 파일 %d개:\n", len(r.Skipped))
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "  ⏭️ %s: %s\n", s.Rel, s.Reason)
+	}
+}
+
+// WriteReport는 검색 결과를 파일로 저장합니다.
+func (r *Result) WriteReport(reportPath string) error {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return fmt.Errorf("모델 검색 보고서 작성 실패 [%s]: %v", reportPath, err)
+	}
+	defer f.Close()
+	r.Print(f)
+	return nil
+}
+
+func isModelFile(rel string) bool {
+	return rankOf(rel) > 0
+}
+
+func rankOf(rel string) int {
+	return extRank[strings.ToLower(path.Ext(rel))]
+}
+
+// isLegacyLayout은 rel이 기존 배치 "<Name>/<Name>.slx" 또는 "<Name>/<Name>.mdl"인지 반환합니다.
+func isLegacyLayout(rel string) bool {
+	dir, file := path.Split(rel)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" || strings.Contains(dir, "/") {
+		return false
+	}
+	ext := path.Ext(file)
+	return (ext == ".slx" || ext == ".mdl") && file[:len(file)-len(ext)] == dir
+}
+
+// swcName은 모델 파일의 SWC 이름을 정합니다.
+//  1. nameMap에 상대 경로, 파일 이름, 확장자를 뺀 파일 이름 중 하나가 있으면 그 값
+//  2. nameRe가 확장자를 뺀 파일 이름과 일치하면 "swc" 이름의 그룹(없으면 첫 번째 그룹) 값. 예: `^(.+?)_v\d+$`
+//  3. 그 밖에는 확장자를 뺀 파일 이름
+func swcName(rel string, nameMap map[string]string, nameRe *regexp.Regexp) string {
+	file := path.Base(rel)
+	base := file[:len(file)-len(path.Ext(file))]
+
+	for _, key := range []string{rel, file, base} {
+		if name, ok := nameMap[key]; ok && strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+
+	if nameRe != nil {
+		if m := nameRe.FindStringSubmatch(base); m != nil && len(m) > 1 {
+			idx := 1
+			if i := nameRe.SubexpIndex("swc"); i > 0 {
+				idx = i
+			}
+			if m[idx] != "" {
+				return m[idx]
+			}
+		}
+	}
+	return base
+}
+
+// matchAny는 rel과 일치하는 첫 번째 패턴을 반환합니다.
+func matchAny(patterns []string, rel string) (string, bool) {
+	for _, p := range patterns {
+		if MatchGlob(p, rel) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// ValidateGlob은 glob 패턴의 각 구간이 path.Match 규칙에 맞는지 검사합니다("**" 구간은 그대로 허용).
+func ValidateGlob(pattern string) error {
+	for _, seg := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchGlob은 "/" 구분 경로 rel이 glob 패턴과 일치하는지 반환합니다.
+// 각 구간은 path.Match 규칙을 따르며, "**" 구간은 0개 이상의 구간과 일치합니다.
+// 잘못된 패턴은 일치하지 않는 것으로 보므로, 패턴은 미리 ValidateGlob으로 검사합니다.
+func MatchGlob(pattern, rel string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pat[0], segs[0]); err != nil || !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -0,0 +1,204 @@
+// Model_Lint.go
+package Model_Lint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"FCU_Tools/M1/Connection_Analysis"
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Reader"
+	"FCU_Tools/M1/Port_Analysis"
+)
+
+// system 파일마다 선이 연결되지 않은 포트, Ground/Terminator 자리 표시 블록, 끝이 없는 선을 찾습니다.
+// 검토자가 모델에서 직접 찾던 결함을 SubSystem(부모 노드)별로 보고하고, LDI 요소마다 개수를 기록합니다.
+
+// LDI 속성 이름(요소의 SubSystem 안에서 찾은 결함 수)입니다.
+const PropDangling = "m1.dangling"
+
+// 결함 종류(LintFinding.Kind)입니다.
+const (
+	UnconnectedPort = "unconnected_port" // 선이 연결되지 않은 블록의 입력/출력 포트(Inport/Outport 블록 포함)
+	Placeholder     = "placeholder"      // Ground/Terminator로 막아 둔 입력/출력
+	DanglingLine    = "dangling_line"    // Dst 없이 끝나는 선(Branch 포함)
+	NoSourceLine    = "no_source_line"   // Src가 없는 선
+)
+
+// placeholderBlockTypes는 연결을 막아 두는 자리 표시 블록입니다.
+var placeholderBlockTypes = map[string]bool{"Ground": true, "Terminator": true}
+
+// LintFile은 model의 system 파일 하나를 점검하여 결함 목록을 반환합니다.
+// 포트는 Port_Analysis.BlockPortsInFile, 연결은 Connection_Analysis로 구합니다.
+func LintFile(model Model_Reader.Model, file string) ([]M1_Public_Data.LintFinding, error) {
+	blocks, err := Port_Analysis.BlockPortsInFile(model, file)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := Connection_Analysis.AnalyzeConnectionsInFile(model, file)
+	if err != nil {
+		return nil, err
+	}
+	dangling, err := Connection_Analysis.AnalyzeDanglingLinesInFile(model, file)
+	if err != nil {
+		return nil, err
+	}
+
+	fanOut := Connection_Analysis.FanOut(edges)
+	fanIn := Connection_Analysis.FanIn(edges)
+	// 끝이 열린 선도 포트에 닿아 있으므로, 그 포트는 연결된 것으로 보고 선의 결함으로만 셉니다.
+	touched := make(map[Connection_Analysis.Endpoint]bool)
+	for _, dl := range dangling {
+		touched[dl.Src] = true
+		for _, dst := range dl.Dsts {
+			touched[dst] = true
+		}
+	}
+	bySID := make(map[string]Port_Analysis.BlockPorts, len(blocks))
+	for _, b := range blocks {
+		bySID[b.SID] = b
+	}
+
+	var findings []M1_Public_Data.LintFinding
+	for _, b := range blocks {
+		if placeholderBlockTypes[b.BlockType] {
+			findings = append(findings, M1_Public_Data.LintFinding{Kind: Placeholder, SID: b.SID, Block: b.Name, BlockType: b.BlockType})
+		}
+		for _, ep := range b.Ports {
+			connected := fanIn[ep] > 0
+			if ep.Kind == "out" {
+				connected = fanOut[ep] > 0
+			}
+			if connected || touched[ep] {
+				continue
+			}
+			findings = append(findings, M1_Public_Data.LintFinding{
+				Kind:      UnconnectedPort,
+				SID:       b.SID,
+				Block:     b.Name,
+				BlockType: b.BlockType,
+				Port:      portText(ep),
+			})
+		}
+	}
+
+	for _, dl := range dangling {
+		f := M1_Public_Data.LintFinding{Line: dl.Line}
+		if dl.Src.SID == "" {
+			f.Kind = NoSourceLine
+			f.Dst = dstText(dl.Dsts)
+		} else {
+			f.Kind = DanglingLine
+			f.SID = dl.Src.SID
+			f.Block = bySID[dl.Src.SID].Name
+			f.BlockType = bySID[dl.Src.SID].BlockType
+			f.Port = portText(dl.Src)
+		}
+		// 여러 끝이 열린 선은 끝마다 하나의 결함으로 셉니다.
+		n := dl.OpenEnds
+		if n < 1 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// portText는 Endpoint에서 블록 SID를 뺀 포트 표기("in:2", "trigger")입니다.
+func portText(ep Connection_Analysis.Endpoint) string {
+	if ep.Index > 0 {
+		return fmt.Sprintf("%s:%d", ep.Kind, ep.Index)
+	}
+	return ep.Kind
+}
+
+// dstText는 선의 입력 포트를 모델 표기("69#in:1, 70#trigger")로 나열합니다.
+func dstText(dsts []Connection_Analysis.Endpoint) string {
+	parts := make([]string, 0, len(dsts))
+	for _, ep := range dsts {
+		parts = append(parts, ep.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CountByFatherPath는 모델의 결함 수를 부모 노드의 SID 경로별로 셉니다(system_root의 결함은 빈 문자열).
+func CountByFatherPath(systems []M1_Public_Data.LintSystem) map[string]int {
+	counts := make(map[string]int)
+	for _, s := range systems {
+		counts[s.FatherPath] += len(s.Findings)
+	}
+	return counts
+}
+
+// Total은 모델들의 전체 결함 수입니다.
+func Total(results []*M1_Public_Data.ModelResult) int {
+	n := 0
+	for _, res := range results {
+		for _, s := range res.Lint {
+			n += len(s.Findings)
+		}
+	}
+	return n
+}
+
+// Print는 모델별, SubSystem별 결함 목록을 w에 출력합니다. 결함이 없는 system은 생략합니다.
+func Print(w io.Writer, results []*M1_Public_Data.ModelResult) {
+	for _, res := range results {
+		total := 0
+		for _, s := range res.Lint {
+			total += len(s.Findings)
+		}
+		fmt.Fprintf(w, "[%s] 결함 %d개\n", res.Name, total)
+
+		for _, s := range res.Lint {
+			if len(s.Findings) == 0 {
+				continue
+			}
+			father := s.Father
+			if s.FatherPath == "" {
+				father = res.Name
+			}
+			fmt.Fprintf(w, "  [L%d] %s (%s, path=%s): %d개\n", s.Level, father, s.File, s.FatherPath, len(s.Findings))
+
+			findings := append([]M1_Public_Data.LintFinding(nil), s.Findings...)
+			sort.SliceStable(findings, func(i, j int) bool { return findings[i].Kind < findings[j].Kind })
+			for _, f := range findings {
+				fmt.Fprintf(w, "    %s\t%s\n", f.Kind, findingText(f))
+			}
+		}
+	}
+}
+
+// findingText는 결함 하나를 "블록(BlockType, SID=..) port=.. dst=.. line=.." 형식으로 만듭니다.
+func findingText(f M1_Public_Data.LintFinding) string {
+	s := ""
+	if f.SID != "" {
+		s = fmt.Sprintf("%s(%s, SID=%s)", f.Block, f.BlockType, f.SID)
+	}
+	if f.Port != "" {
+		s += " port=" + f.Port
+	}
+	if f.Dst != "" {
+		s += " dst=" + f.Dst
+	}
+	if f.Line != "" {
+		s += " line=" + f.Line
+	}
+	return s
+}
+
+// WriteReport는 결함 목록을 파일로 저장합니다.
+func WriteReport(reportPath string, results []*M1_Public_Data.ModelResult) error {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return fmt.Errorf("모델 점검 보고서 작성 실패 [%s]: %v", reportPath, err)
+	}
+	defer f.Close()
+	Print(f, results)
+	return nil
+}
@@ -0,0 +1,80 @@
+package Model_Lint
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Reader"
+)
+
+// memModel은 내부 파일을 메모리에 담은 테스트용 Model입니다.
+type memModel struct {
+	name  string
+	files map[string]string
+}
+
+func (m *memModel) Name() string { return m.name }
+
+func (m *memModel) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", name, os.ErrNotExist)
+	}
+	return []byte(data), nil
+}
+
+func (m *memModel) Exists(name string) bool {
+	_, ok := m.files[name]
+	return ok
+}
+
+func (m *memModel) Location(name string) string { return m.name + "!" + name }
+func (m *memModel) Close() error                { return nil }
+
+// Gain 5의 출력은 Dst 없이 끝나고, Gain 6의 입력에는 Src가 없는 선이 들어옵니다.
+const openLinesSystem = `<?xml version="1.0" encoding="utf-8"?>
+<System>
+  <Block BlockType="Gain" Name="G5" SID="5">
+    <P Name="Ports">[1, 1]</P>
+  </Block>
+  <Block BlockType="Gain" Name="G6" SID="6">
+    <P Name="Ports">[1, 1]</P>
+  </Block>
+  <Line>
+    <P Name="Name">open_out</P>
+    <P Name="Src">5#out:1</P>
+  </Line>
+  <Line>
+    <P Name="Name">no_src</P>
+    <P Name="Dst">6#in:1</P>
+  </Line>
+</System>`
+
+func TestLintFileOpenLines(t *testing.T) {
+	model := &memModel{
+		name:  "M",
+		files: map[string]string{Model_Reader.SystemPath("system_root.xml"): openLinesSystem},
+	}
+	findings, err := LintFile(model, "system_root.xml")
+	if err != nil {
+		t.Fatalf("LintFile: %v", err)
+	}
+
+	// 열린 선이 닿은 포트(5#out:1, 6#in:1)는 선의 결함으로만 세고, 연결되지 않은 포트는 G5 in:1, G6 out:1뿐입니다.
+	want := map[M1_Public_Data.LintFinding]bool{
+		{Kind: UnconnectedPort, SID: "5", Block: "G5", BlockType: "Gain", Port: "in:1"}:                 true,
+		{Kind: UnconnectedPort, SID: "6", Block: "G6", BlockType: "Gain", Port: "out:1"}:                true,
+		{Kind: DanglingLine, SID: "5", Block: "G5", BlockType: "Gain", Port: "out:1", Line: "open_out"}: true,
+		{Kind: NoSourceLine, Dst: "6#in:1", Line: "no_src"}:                                             true,
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("결함 수 = %d, want %d: %+v", len(findings), len(want), findings)
+	}
+	for _, f := range findings {
+		if !want[f] {
+			t.Errorf("예상하지 않은 결함: %+v", f)
+		}
+	}
+}
@@ -0,0 +1,584 @@
+// Model_Reader.go
+package Model_Reader
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Mdl_Parser"
+)
+
+// slx 내부의 고정 경로(압축 파일 안의 경로이므로 항상 "/" 구분자를 사용합니다)
+const (
+	SystemsDir             = "simulink/systems"
+	RootSystemFile         = "system_root.xml"
+	GraphicalInterfaceFile = "simulink/graphicalInterface.xml"
+
+	// 최신 Simulink는 인터페이스를 JSON으로 저장합니다(graphicalInterface.xml이 없을 때 사용).
+	GraphicalInterfaceJSONFile = "simulink/graphicalInterface.json"
+)
+
+// SystemFile은 SID에 해당하는 system_<SID>.xml 파일 이름을 반환합니다.
+func SystemFile(sid string) string {
+	return fmt.Sprintf("system_%s.xml", sid)
+}
+
+// SystemPath는 system_xxx.xml 파일 이름을 slx 내부 경로(simulink/systems/...)로 바꿉니다.
+func SystemPath(file string) string {
+	return path.Join(SystemsDir, file)
+}
+
+// Model은 모델 하나(slx)의 내부 파일을 읽는 방법을 추상화합니다.
+// slx 압축 파일을 메모리에서 바로 읽는 구현(OpenSlx), 디스크에 풀어 둔 폴더를 읽는 구현(OpenDir),
+// 텍스트 형식의 .mdl을 slx와 같은 내부 파일로 변환하는 구현(OpenMdl)이 있습니다.
+// name은 slx 내부 경로입니다. 예: "simulink/systems/system_root.xml"
+type Model interface {
+	Name() string                         // 모델 이름(slx/mdl 파일명에서 확장자를 뺀 값)
+	ReadFile(name string) ([]byte, error) // 내부 파일 내용
+	Exists(name string) bool              // 내부 파일 존재 여부
+	Location(name string) string          // 오류 메시지에 표시할 위치
+	Close() error
+}
+
+// Open은 확장자에 따라 .mdl은 OpenMdl, 그 밖(.slx/.slxp)은 OpenSlx로 엽니다.
+func Open(modelPath string, limits M1_Public_Data.ExtractLimits) (Model, error) {
+	if strings.EqualFold(filepath.Ext(modelPath), ".mdl") {
+		return OpenMdl(modelPath, limits)
+	}
+	return OpenSlx(modelPath, limits)
+}
+
+// Rename은 m을 name이라는 이름으로 보이게 합니다(모델 검색 규칙에서 정한 SWC 이름 적용용).
+func Rename(m Model, name string) Model {
+	if m.Name() == name {
+		return m
+	}
+	return &renamedModel{Model: m, name: name}
+}
+
+type renamedModel struct {
+	Model
+	name string
+}
+
+func (m *renamedModel) Name() string { return m.name }
+
+// ======================== slx(zip)를 메모리에서 읽기 ========================
+
+type zipModel struct {
+	name   string
+	path   string
+	r      *zip.ReadCloser
+	files  map[string]*zip.File
+	limits M1_Public_Data.ExtractLimits
+
+	// 같은 system_xxx.xml을 System/Port/Connection 분석이 각각 읽으므로 압축 해제한 내용을 보관합니다.
+	// total은 지금까지 압축 해제한(cache에 보관한) 전체 크기이며, limits.MaxTotalBytes를 넘지 않도록 합니다.
+	mu    sync.Mutex
+	cache map[string][]byte
+	total int64
+}
+
+// OpenSlx는 slx 파일을 열어 내부 파일을 메모리에서 읽는 Model을 반환합니다. 디스크에 압축을 풀지 않습니다.
+// limits의 항목 수/항목 크기/전체 크기 제한은 읽을 때 적용합니다.
+func OpenSlx(slxPath string, limits M1_Public_Data.ExtractLimits) (Model, error) {
+	r, err := zip.OpenReader(slxPath)
+	if err != nil {
+		return nil, fmt.Errorf("손상되었거나 zip 형식이 아닌 slx 파일입니다 [%s]: %v", slxPath, err)
+	}
+	if limits.MaxEntries > 0 && len(r.File) > limits.MaxEntries {
+		r.Close()
+		return nil, fmt.Errorf("압축 파일의 항목 수(%d)가 제한(%d)을 초과합니다 [%s]", len(r.File), limits.MaxEntries, slxPath)
+	}
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		if f.Mode().IsRegular() {
+			files[path.Clean(filepath.ToSlash(f.Name))] = f
+		}
+	}
+
+	base := filepath.Base(slxPath)
+	return &zipModel{
+		name:   base[:len(base)-len(filepath.Ext(base))],
+		path:   slxPath,
+		r:      r,
+		files:  files,
+		limits: limits,
+		cache:  make(map[string][]byte),
+	}, nil
+}
+
+func (m *zipModel) Name() string { return m.name }
+
+func (m *zipModel) Exists(name string) bool {
+	_, ok := m.files[path.Clean(name)]
+	return ok
+}
+
+func (m *zipModel) Location(name string) string {
+	return m.path + "!" + name
+}
+
+func (m *zipModel) ReadFile(name string) ([]byte, error) {
+	name = path.Clean(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data, ok := m.cache[name]; ok {
+		return data, nil
+	}
+
+	f, ok := m.files[name]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", m.Location(name), os.ErrNotExist)
+	}
+	if m.limits.MaxEntryBytes > 0 && f.UncompressedSize64 > uint64(m.limits.MaxEntryBytes) {
+		return nil, fmt.Errorf("항목 [%s]의 크기(%d바이트)가 제한(%d바이트)을 초과합니다", m.Location(name), f.UncompressedSize64, m.limits.MaxEntryBytes)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("항목 [%s]을 열 수 없습니다(손상된 압축 파일): %v", m.Location(name), err)
+	}
+	defer rc.Close()
+
+	// 헤더의 크기 정보는 조작될 수 있으므로 실제로 읽은 크기로 다시 확인합니다.
+	// 항목 제한과 남은 전체 제한 중 작은 값까지만 읽습니다.
+	limit := m.limits.MaxEntryBytes
+	if m.limits.MaxTotalBytes > 0 {
+		remaining := m.limits.MaxTotalBytes - m.total
+		if limit <= 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	var src io.Reader = rc
+	if limit > 0 || m.limits.MaxTotalBytes > 0 {
+		src = io.LimitReader(rc, limit+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("항목 [%s] 읽기 실패(손상된 압축 파일): %v", m.Location(name), err)
+	}
+	if m.limits.MaxEntryBytes > 0 && int64(len(data)) > m.limits.MaxEntryBytes {
+		return nil, fmt.Errorf("항목 [%s]의 실제 크기가 제한(%d바이트)을 초과합니다", m.Location(name), m.limits.MaxEntryBytes)
+	}
+	if m.limits.MaxTotalBytes > 0 && m.total+int64(len(data)) > m.limits.MaxTotalBytes {
+		return nil, fmt.Errorf("압축 해제한 전체 크기가 제한(%d바이트)을 초과합니다 [%s]", m.limits.MaxTotalBytes, m.Location(name))
+	}
+
+	m.total += int64(len(data))
+	m.cache[name] = data
+	return data, nil
+}
+
+func (m *zipModel) Close() error {
+	m.mu.Lock()
+	m.cache = nil
+	m.mu.Unlock()
+	return m.r.Close()
+}
+
+// ======================== 디스크에 풀어 둔 폴더 읽기(디버그 모드) ========================
+
+type dirModel struct {
+	name string
+	root string
+}
+
+// OpenDir는 slx를 풀어 둔 폴더(BuildDir/<Model>)를 읽는 Model을 반환합니다.
+func OpenDir(name, root string) Model {
+	return &dirModel{name: name, root: root}
+}
+
+func (m *dirModel) Name() string { return m.name }
+
+func (m *dirModel) Exists(name string) bool {
+	_, err := os.Stat(m.Location(name))
+	return err == nil
+}
+
+func (m *dirModel) Location(name string) string {
+	return filepath.Join(m.root, filepath.FromSlash(name))
+}
+
+func (m *dirModel) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(m.Location(name))
+}
+
+func (m *dirModel) Close() error { return nil }
+
+// ======================== 라이브러리 링크/참조 모델 찾기 ========================
+
+// Resolver는 라이브러리 링크(Reference)와 모델 참조(ModelReference)가 가리키는 모델을
+// 모델 디렉터리 아래의 .slx/.mdl/.slxp 파일에서 찾아 엽니다.
+// 한 번 연 모델은 다시 사용하며, 여러 워커가 동시에 사용해도 안전합니다.
+type Resolver struct {
+	limits M1_Public_Data.ExtractLimits
+	paths  map[string]string // 모델 이름 → 파일 경로(같은 이름이면 .slx, .mdl, .slxp 순으로 우선)
+
+	mu     sync.Mutex
+	opened map[string]Model
+	failed map[string]error
+}
+
+// NewResolver는 root 아래(하위 폴더 포함)의 .slx/.mdl/.slxp 파일을 모델 이름으로 색인합니다.
+// 심볼릭 링크는 따라가지 않습니다.
+func NewResolver(root string, limits M1_Public_Data.ExtractLimits) (*Resolver, error) {
+	r := &Resolver{
+		limits: limits,
+		paths:  make(map[string]string),
+		opened: make(map[string]Model),
+		failed: make(map[string]error),
+	}
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rank := extRank(p)
+		if rank == 0 {
+			return nil
+		}
+		base := filepath.Base(p)
+		name := base[:len(base)-len(filepath.Ext(base))]
+		if old, ok := r.paths[name]; ok && extRank(old) >= rank {
+			return nil // 같은 이름이면 먼저 찾은 파일을 사용하되, 형식의 우선순위가 높은 파일로 바꿉니다.
+		}
+		r.paths[name] = p
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("참조 모델 검색 실패 [%s]: %v", root, err)
+	}
+	return r, nil
+}
+
+// extRank는 같은 이름의 모델 파일 중 사용할 형식의 우선순위입니다(0이면 모델 파일이 아님).
+func extRank(p string) int {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".slx":
+		return 3
+	case ".mdl":
+		return 2
+	case ".slxp":
+		return 1
+	}
+	return 0
+}
+
+// IsProtected는 파일이 보호된 모델(.slxp)인지 반환합니다. 보호된 모델은 내부 구조가 암호화되어 있어 분석할 수 없습니다.
+func IsProtected(p string) bool {
+	return strings.EqualFold(filepath.Ext(p), ".slxp")
+}
+
+// Open은 이름에 해당하는 모델을 엽니다(".slx", ".mdl" 등 확장자가 붙어 있어도 됩니다).
+// 모델 디렉터리에 없으면 os.ErrNotExist를 감싼 오류를 반환합니다.
+func (r *Resolver) Open(name string) (Model, error) {
+	name = strings.TrimSpace(name)
+	if extRank(name) > 0 {
+		name = name[:len(name)-len(path.Ext(name))]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.opened[name]; ok {
+		return m, nil
+	}
+	if err, ok := r.failed[name]; ok {
+		return nil, err
+	}
+
+	p, ok := r.paths[name]
+	if !ok {
+		err := fmt.Errorf("모델 디렉터리에서 %s(.slx/.mdl/.slxp)를 찾을 수 없습니다: %w", name, os.ErrNotExist)
+		r.failed[name] = err
+		return nil, err
+	}
+	m, err := Open(p, r.limits)
+	if err != nil {
+		r.failed[name] = err
+		return nil, err
+	}
+	r.opened[name] = m
+	return m, nil
+}
+
+// Path는 이름에 해당하는 모델 파일의 경로를 반환합니다.
+func (r *Resolver) Path(name string) (string, bool) {
+	p, ok := r.paths[name]
+	return p, ok
+}
+
+// Close는 Resolver가 연 모든 모델을 닫습니다.
+func (r *Resolver) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.opened {
+		_ = m.Close()
+	}
+	r.opened = make(map[string]Model)
+}
+
+// ======================== 텍스트 형식(.mdl) 모델 읽기 ========================
+
+type mdlModel struct {
+	name  string
+	path  string
+	files map[string][]byte // slx 내부 경로 → 변환한 XML
+}
+
+// OpenMdl은 텍스트 형식의 .mdl 파일을 파싱하여, slx와 같은 내부 파일(simulink/systems/system_*.xml,
+// simulink/graphicalInterface.xml)을 메모리에 만든 Model을 반환합니다. 따라서 분석 단계는 두 형식을 구분하지 않습니다.
+// SID가 없는 오래된 .mdl의 블록에는 모델 안에서 겹치지 않는 SID를 새로 붙입니다.
+func OpenMdl(mdlPath string, limits M1_Public_Data.ExtractLimits) (Model, error) {
+	info, err := os.Stat(mdlPath)
+	if err != nil {
+		return nil, fmt.Errorf("mdl 파일을 열 수 없습니다 [%s]: %v", mdlPath, err)
+	}
+	if limits.MaxTotalBytes > 0 && info.Size() > limits.MaxTotalBytes {
+		return nil, fmt.Errorf("mdl 파일의 크기(%d바이트)가 제한(%d바이트)을 초과합니다 [%s]", info.Size(), limits.MaxTotalBytes, mdlPath)
+	}
+	data, err := os.ReadFile(mdlPath)
+	if err != nil {
+		return nil, fmt.Errorf("mdl 파일을 읽을 수 없습니다 [%s]: %v", mdlPath, err)
+	}
+
+	sections, err := Mdl_Parser.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("mdl 파싱 실패 [%s]: %v", mdlPath, err)
+	}
+	var top *Mdl_Parser.Section
+	for _, s := range sections {
+		if s.Type == "Model" || s.Type == "Library" {
+			top = s
+			break
+		}
+	}
+	if top == nil {
+		return nil, fmt.Errorf("mdl 파일에 Model/Library 구간이 없습니다 [%s]", mdlPath)
+	}
+	root := top.Child("System")
+	if root == nil {
+		return nil, fmt.Errorf("mdl 파일에 System 구간이 없습니다 [%s]", mdlPath)
+	}
+
+	c := &mdlConverter{files: make(map[string][]byte), sids: make(map[*Mdl_Parser.Section]string)}
+	c.assignSIDs(root)
+	c.writeSystem(RootSystemFile, root)
+	if gi := top.Child("GraphicalInterface"); gi != nil {
+		c.files[GraphicalInterfaceFile] = graphicalInterfaceXML(gi)
+	}
+
+	base := filepath.Base(mdlPath)
+	return &mdlModel{
+		name:  base[:len(base)-len(filepath.Ext(base))],
+		path:  mdlPath,
+		files: c.files,
+	}, nil
+}
+
+func (m *mdlModel) Name() string { return m.name }
+
+func (m *mdlModel) Exists(name string) bool {
+	_, ok := m.files[path.Clean(name)]
+	return ok
+}
+
+func (m *mdlModel) Location(name string) string {
+	return m.path + "!" + name
+}
+
+func (m *mdlModel) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", m.Location(name), os.ErrNotExist)
+	}
+	return data, nil
+}
+
+func (m *mdlModel) Close() error { return nil }
+
+// mdlConverter는 .mdl의 System 구간을 system_*.xml 형식으로 바꿉니다.
+type mdlConverter struct {
+	files   map[string][]byte
+	sids    map[*Mdl_Parser.Section]string // Block 구간 → SID
+	nextSID int
+}
+
+// assignSIDs는 모든 Block의 SID를 정합니다. SID가 없으면 기존 최대 SID 다음 번호를 붙입니다.
+func (c *mdlConverter) assignSIDs(sys *Mdl_Parser.Section) {
+	var missing []*Mdl_Parser.Section
+	var walk func(s *Mdl_Parser.Section)
+	walk = func(s *Mdl_Parser.Section) {
+		for _, b := range s.ChildrenOf("Block") {
+			if sid := b.Value("SID"); sid != "" {
+				c.sids[b] = sid
+				if n, err := strconv.Atoi(sid); err == nil && n >= c.nextSID {
+					c.nextSID = n + 1
+				}
+			} else {
+				missing = append(missing, b)
+			}
+			if child := b.Child("System"); child != nil {
+				walk(child)
+			}
+		}
+	}
+	walk(sys)
+
+	if c.nextSID == 0 {
+		c.nextSID = 1
+	}
+	for _, b := range missing {
+		c.sids[b] = strconv.Itoa(c.nextSID)
+		c.nextSID++
+	}
+}
+
+// writeSystem은 System 구간 하나를 file(system_xxx.xml)로 변환하고, 하위 System은 system_<SID>.xml로 변환합니다.
+func (c *mdlConverter) writeSystem(file string, sys *Mdl_Parser.Section) {
+	blocks := sys.ChildrenOf("Block")
+
+	// Line은 블록 이름으로 연결을 표기하므로 SID로 바꾸기 위한 매핑입니다.
+	sidByName := make(map[string]string, len(blocks))
+	for _, b := range blocks {
+		sidByName[b.Value("Name")] = c.sids[b]
+	}
+
+	var x strings.Builder
+	x.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<System>\n")
+	for _, b := range blocks {
+		sid := c.sids[b]
+		fmt.Fprintf(&x, "  <Block BlockType=\"%s\" Name=\"%s\" SID=\"%s\">\n", xmlEscape(b.Value("BlockType")), xmlEscape(b.Value("Name")), xmlEscape(sid))
+		for _, p := range b.Params {
+			switch p.Key {
+			case "BlockType", "Name", "SID":
+				continue
+			}
+			writeP(&x, "    ", p)
+		}
+		// 출력 포트의 신호 이름(Port { PortNumber 1 Name "sig" })은 slx와 같이 <Port>로 기록합니다.
+		for _, port := range b.ChildrenOf("Port") {
+			x.WriteString("    <Port>\n")
+			for _, p := range port.Params {
+				writeP(&x, "      ", p)
+			}
+			x.WriteString("    </Port>\n")
+		}
+		if mdlMasked(b) {
+			x.WriteString("    <Mask/>\n")
+		}
+		x.WriteString("  </Block>\n")
+
+		if child := b.Child("System"); child != nil {
+			c.writeSystem(SystemFile(sid), child)
+		}
+	}
+	for _, l := range sys.ChildrenOf("Line") {
+		x.WriteString("  <Line>\n")
+		writeLineEnds(&x, "    ", l, sidByName)
+		x.WriteString("  </Line>\n")
+	}
+	x.WriteString("</System>\n")
+
+	c.files[SystemPath(file)] = []byte(x.String())
+}
+
+// writeLineEnds는 Line/Branch의 이름과 Src/Dst를 slx 형식("SID#out:1", "SID#in:2", "SID#trigger")으로 기록합니다.
+// 최신 .mdl처럼 이미 Src/Dst가 있으면 그대로 사용합니다.
+func writeLineEnds(x *strings.Builder, indent string, l *Mdl_Parser.Section, sidByName map[string]string) {
+	if name, ok := l.Param("Name"); ok {
+		writeP(x, indent, Mdl_Parser.Param{Key: "Name", Value: name})
+	}
+	if src, ok := l.Param("Src"); ok {
+		writeP(x, indent, Mdl_Parser.Param{Key: "Src", Value: src})
+	} else if name, ok := l.Param("SrcBlock"); ok {
+		if sid, ok := sidByName[name]; ok {
+			writeP(x, indent, Mdl_Parser.Param{Key: "Src", Value: endpoint(sid, "out", l.Value("SrcPort"))})
+		}
+	}
+	if dst, ok := l.Param("Dst"); ok {
+		writeP(x, indent, Mdl_Parser.Param{Key: "Dst", Value: dst})
+	} else if name, ok := l.Param("DstBlock"); ok {
+		if sid, ok := sidByName[name]; ok {
+			writeP(x, indent, Mdl_Parser.Param{Key: "Dst", Value: endpoint(sid, "in", l.Value("DstPort"))})
+		}
+	}
+	for _, br := range l.ChildrenOf("Branch") {
+		x.WriteString(indent + "<Branch>\n")
+		writeLineEnds(x, indent+"  ", br, sidByName)
+		x.WriteString(indent + "</Branch>\n")
+	}
+}
+
+// endpoint는 포트 번호가 숫자이면 "SID#out:1"처럼, trigger/enable 등 특수 포트이면 "SID#trigger"처럼 만듭니다.
+func endpoint(sid, dir, port string) string {
+	port = strings.TrimSpace(port)
+	if port == "" {
+		return sid
+	}
+	if _, err := strconv.Atoi(port); err == nil {
+		return sid + "#" + dir + ":" + port
+	}
+	return sid + "#" + port
+}
+
+// mdlMasked는 블록에 마스크가 적용되어 있는지 반환합니다(Mask 구간, Mask on 또는 MaskType 등의 파라미터).
+func mdlMasked(b *Mdl_Parser.Section) bool {
+	if b.Child("Mask") != nil {
+		return true
+	}
+	for _, p := range b.Params {
+		if p.Key == "Mask" && p.Value == "on" {
+			return true
+		}
+		if p.Key == "MaskType" || p.Key == "MaskPromptString" || p.Key == "MaskVariables" {
+			return true
+		}
+	}
+	return false
+}
+
+// graphicalInterfaceXML은 GraphicalInterface 구간을 graphicalInterface.xml 형식으로 바꿉니다
+// (RequireFunction/ProvideFunction 등 하위 구간은 같은 이름의 태그, 파라미터는 P 태그).
+func graphicalInterfaceXML(gi *Mdl_Parser.Section) []byte {
+	var x strings.Builder
+	x.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<GraphicalInterface>\n")
+	for _, p := range gi.Params {
+		writeP(&x, "  ", p)
+	}
+	for _, child := range gi.Children {
+		fmt.Fprintf(&x, "  <%s>\n", child.Type)
+		for _, p := range child.Params {
+			writeP(&x, "    ", p)
+		}
+		fmt.Fprintf(&x, "  </%s>\n", child.Type)
+	}
+	x.WriteString("</GraphicalInterface>\n")
+	return []byte(x.String())
+}
+
+func writeP(x *strings.Builder, indent string, p Mdl_Parser.Param) {
+	fmt.Fprintf(x, "%s<P Name=\"%s\">%s</P>\n", indent, xmlEscape(p.Key), xmlEscape(p.Value))
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -3,14 +3,16 @@ package Port_Analysis
 import (
 	"encoding/xml"
 	"fmt"
-	"os"
-	"path/filepath"
+	"path"
 	"sort" // ===== NEW =====
+	"strconv"
 	"strings"
 
 	"FCU_Tools/M1/C_S_Analysis"
 	"FCU_Tools/M1/Connection_Analysis"
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Reader"
+	"FCU_Tools/Public_data"
 )
 
 // Port 정보를 저장하는 데 사용됩니다.
@@ -20,14 +22,32 @@ type PortInfo struct {
 	Level     int
 	BlockType string
 	PortType  string
-	Virtual   bool // true는 의사 포트(블록-블록 연결로 생성된 가상 포트)를 의미합니다. 사용하지 않음.
+	Kind      string // S-R 포트 종류(Public_data.M1PortKinds)
+	Virtual   bool   // true는 의사 포트(블록-블록 연결로 생성된 가상 포트)를 의미합니다. virtualPorts일 때만 생성됩니다.
 }
 
-// 블록(여기서는 BlockType / Name / SID만 관심)
+// P 태그
+type xmlP struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// 블록(여기서는 BlockType / Name / SID와 포트 종류를 정하는 P만 관심)
 type xmlBlock struct {
-	BlockType string `xml:"BlockType,attr"`
-	Name      string `xml:"Name,attr"`
-	SID       string `xml:"SID,attr"`
+	BlockType  string `xml:"BlockType,attr"`
+	Name       string `xml:"Name,attr"`
+	SID        string `xml:"SID,attr"`
+	Properties []xmlP `xml:"P"`
+}
+
+// property는 이름에 해당하는 P 태그의 값을 반환합니다(없으면 빈 문자열).
+func (b xmlBlock) property(name string) string {
+	for _, p := range b.Properties {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
 }
 
 type xmlSystem struct {
@@ -41,20 +61,20 @@ type connectItem struct {
 	Strength int
 }
 
-// 지정된 디렉터리와 파일의 Port + 블록-블록 연결 정보를 분석합니다.
-// 출력 형식：
-// [Lx] Name: <BlockName>	BlockType=<BlockType>	SID=<SID> [FatherNode=xxx]
-//
-//	[Lx Port] Name: <真实Port名>	BlockType=<In/Outport>	SID=<SID> [PortType=S-R]
-//	[Lx virtual Port] Name: <BlockA->BlockB[_n]>	BlockType=<In/Outport>	SID=<69->147> ...
+// 지정된 모델 파일의 Port + 블록-블록 연결 정보를 분석하여 res.Blocks에 Block(포트/연결 포함)으로 추가합니다.
+// (사람이 읽을 수 있는 txt/JSON 형식의 출력은 File_Utils_M1의 덤프 함수가 담당합니다)
 //
+// fatherPath: 부모 노드의 SID 경로(L1은 빈 문자열)이며, 각 Block의 Path는 이 경로 뒤에 Block SID를 붙인 값입니다.
 // blockSIDs: 본 레이어의 System_Analysis에서 필터링된 Block SID 목록이며, 이 Block들에 대해서만 출력합니다.
 //
 //	비어 있으면 “level에 따라 자동 선택” 로직으로 되돌아갑니다.
-func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName string, blockSIDs []string) error {
-	fullPath := filepath.Join(dir, file)
+//
+// virtualPorts: true이면 블록-블록 직접 연결(상황 3)마다 양쪽 블록에 가상 Outport/Inport(Virtual=true)를 추가합니다.
+// 모델마다 별도의 res를 사용하므로 병렬로 호출해도 안전합니다.
+func AnalyzePortsInFile(res *M1_Public_Data.ModelResult, model Model_Reader.Model, file string, level int, virtualPorts bool, fatherName, fatherPath string, blockSIDs []string) error {
+	fullPath := model.Location(Model_Reader.SystemPath(file))
 
-	data, err := os.ReadFile(fullPath)
+	data, err := model.ReadFile(Model_Reader.SystemPath(file))
 	if err != nil {
 		return fmt.Errorf("XML 읽기 실패 [%s]: %w", fullPath, err)
 	}
@@ -90,13 +110,13 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 		// 대체 로직: blockSIDs가 전달되지 않으면 level에 따라 직접 선택합니다.
 		for _, b := range sys.Blocks {
 			if level == 1 || level == 2 {
-				// 1, 2층: SubSystem만 분석합니다.
-				if b.BlockType != "SubSystem" {
+				// 1, 2층: SubSystem(라이브러리 링크/모델 참조 포함)만 분석합니다.
+				if !M1_Public_Data.IsSubSystemLike(b.BlockType) {
 					continue
 				}
 			} else {
-				// 3층 및 이후: Inport/Outport가 아닌 모든 Block
-				if b.BlockType == "Inport" || b.BlockType == "Outport" {
+				// 3층 및 이후: 포트 블록(Inport/Outport/InportShadow/TriggerPort 등)이 아닌 모든 Block
+				if isPortBlockType(b.BlockType) {
 					continue
 				}
 			}
@@ -105,10 +125,17 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 		}
 	}
 
-	// 3）모든 실제 Port(Inport/Outport)를 수집합니다.
+	// 3）선으로 연결되는 모든 실제 Port(Inport/Outport/InportShadow)를 수집합니다.
+	// InportShadow는 같은 Inport의 복제이므로 Inport로 표시합니다. TriggerPort/EnablePort/ActionPort는
+	// 부모 SubSystem의 제어 입력이므로 여기서 세지 않고, 부모 레벨에서 "#trigger" 등으로 들어가는 선으로 셉니다.
 	portInfos := make(map[string]PortInfo)
 	for _, b := range sys.Blocks {
-		if b.BlockType != "Inport" && b.BlockType != "Outport" {
+		blockType := b.BlockType
+		switch blockType {
+		case "Inport", "Outport":
+		case "InportShadow":
+			blockType = "Inport"
+		default:
 			continue
 		}
 		name := normalizeName(b.Name)
@@ -116,18 +143,34 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 			Name:      name,
 			SID:       b.SID,
 			Level:     level,
-			BlockType: b.BlockType,
+			BlockType: blockType,
 			PortType:  "S-R",
+			Kind:      portBlockKind(b),
 			Virtual:   false, // 실제 포트
 		}
 	}
 
 	// 4）Connection_Analysis로 모든 연결 Edge를 파싱합니다.
-	edges, err := Connection_Analysis.AnalyzeConnectionsInFile(dir, file)
+	edges, err := Connection_Analysis.AnalyzeConnectionsInFile(model, file)
 	if err != nil {
 		return fmt.Errorf("연결 관계 분석에 실패했습니다. [%s]: %w", fullPath, err)
 	}
 
+	// BusSelector로 바로 들어가는 Inport와 BusCreator에서 바로 나오는 Outport는 버스 요소 포트로 봅니다.
+	for _, e := range edges {
+		if p, ok := portInfos[e.Src.SID]; ok && p.Kind == Public_data.M1PortKindData && blocksBySID[e.Dst.SID].BlockType == "BusSelector" {
+			p.Kind = Public_data.M1PortKindBusElement
+			portInfos[e.Src.SID] = p
+		}
+		if p, ok := portInfos[e.Dst.SID]; ok && p.Kind == Public_data.M1PortKindData && blocksBySID[e.Src.SID].BlockType == "BusCreator" {
+			p.Kind = Public_data.M1PortKindBusElement
+			portInfos[e.Dst.SID] = p
+		}
+	}
+
+	// 선이 블록의 trigger/enable/ifaction 입력으로 들어가면 포트 종류는 그 입력으로 정합니다.
+	kinds := newKindResolver(model)
+
 	// 4.N은 L2+에서만: SubSystem ↔ SubSystem 연결을 계산(중간 Block은 무시해도 됨)
 	// subsysConnect[srcSID][dstSID] = strength
 	subsysConnect := make(map[string]map[string]int)
@@ -141,8 +184,8 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 		adjSeen := make(map[string]map[string]struct{})
 
 		for _, e := range edges {
-			srcSID := e.SrcSID
-			dstSID := e.DstSID
+			srcSID := e.Src.SID
+			dstSID := e.Dst.SID
 
 			// 시스템에 존재하는 SID만 처리
 			if _, ok := blocksBySID[srcSID]; !ok {
@@ -166,13 +209,13 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 			}
 		}
 
-		// “대상 SubSystem”: selected이어야 하고 BlockType == SubSystem이어야 함
+		// “대상 SubSystem”: selected이어야 하고 BlockType이 SubSystem(라이브러리 링크/모델 참조 포함)이어야 함
 		isTargetSubSystem := func(sid string) bool {
 			if _, ok := selected[sid]; !ok {
 				return false
 			}
 			blk, ok := blocksBySID[sid]
-			return ok && blk.BlockType == "SubSystem"
+			return ok && M1_Public_Data.IsSubSystemLike(blk.BlockType)
 		}
 
 		// reachable(node): node에서 출발해 목표가 아닌 SubSystem은 통과하고, 최종적으로 도달 가능한 목표 SubSystem은 무엇인지
@@ -226,8 +269,8 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 	// 4.1 먼저 각 Block-SID 쌍 사이의 연결 개수를 집계하여, _1/_2 접미사를 추가할지 결정하는 데 사용합니다.
 	pairCounts := make(map[string]int) // srcSID->dstSID → 총 개수
 	for _, e := range edges {
-		srcSID := e.SrcSID
-		dstSID := e.DstSID
+		srcSID := e.Src.SID
+		dstSID := e.Dst.SID
 
 		// Block-Block 연결만 집계하며, 양쪽 모두 유효한 Block이면 됩니다.
 		if _, ok := blocksBySID[srcSID]; !ok {
@@ -254,9 +297,25 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 	seen := make(map[string]map[string]struct{}) // 중복 제거에 사용: blockSID → set(portSID)
 	pairIndex := make(map[string]int)            // srcSID->dstSID → 현재 몇 번째인지, _1/_2에 사용합니다.
 
+	// 실제 포트는 여러 블록에 연결될 수 있으므로, 블록마다 포트 종류를 따로 둡니다: blockSID → portSID → kind
+	attachedKinds := make(map[string]map[string]string)
+	setAttachedKind := func(blockSID, portSID, kind string) {
+		if kind == "" {
+			return
+		}
+		if attachedKinds[blockSID] == nil {
+			attachedKinds[blockSID] = make(map[string]string)
+		}
+		// 같은 포트가 data 입력과 제어 입력에 모두 연결되면 제어 입력 종류를 우선합니다.
+		if old, ok := attachedKinds[blockSID][portSID]; ok && old != Public_data.M1PortKindData {
+			return
+		}
+		attachedKinds[blockSID][portSID] = kind
+	}
+
 	for _, e := range edges {
-		srcSID := e.SrcSID
-		dstSID := e.DstSID
+		srcSID := e.Src.SID
+		dstSID := e.Dst.SID
 
 		_, srcIsPort := portInfos[srcSID]
 		_, dstIsPort := portInfos[dstSID]
@@ -273,6 +332,7 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 				blockToPorts[dstSID] = append(blockToPorts[dstSID], srcSID)
 				seen[dstSID][srcSID] = struct{}{}
 			}
+			setAttachedKind(dstSID, srcSID, kinds.edgeKind(e, portInfos[srcSID].Kind))
 		}
 
 		// 상황 2: Dst가 Port이고 Src가 관심 Block인 경우(전형적: Block → Outport)
@@ -286,9 +346,34 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 			}
 		}
 
+		// 상황 4: 가상 포트를 만들지 않는 레벨에서 Block이 관심 Block의 제어 입력(trigger/enable/ifaction)을 구동하는 경우
+		// (예: Function-Call Generator → 69#trigger). 제어 입력은 블록마다 하나이므로, 대상 블록에 그 종류의 포트를 하나 기록합니다.
+		if !virtualPorts && !srcIsPort && !dstIsPort && dstIsSelectedBlock && isControlInput(e.Dst.Kind) {
+			if srcBlk, ok := blocksBySID[srcSID]; ok {
+				ctrlKey := "ctrl:" + e.Dst.String()
+				if _, ok := portInfos[ctrlKey]; !ok {
+					portInfos[ctrlKey] = PortInfo{
+						Name:      normalizeName(srcBlk.Name),
+						SID:       e.Dst.String(),
+						Level:     level,
+						BlockType: "Inport",
+						PortType:  "S-R",
+						Kind:      kinds.edgeKind(e, Public_data.M1PortKindData),
+					}
+				}
+				if seen[dstSID] == nil {
+					seen[dstSID] = make(map[string]struct{})
+				}
+				if _, ok := seen[dstSID][ctrlKey]; !ok {
+					blockToPorts[dstSID] = append(blockToPorts[dstSID], ctrlKey)
+					seen[dstSID][ctrlKey] = struct{}{}
+				}
+			}
+		}
+
 		// 상황 3: Block과 Block이 직접 연결된 경우(예: 66#out:1 → 69#in:3)
-		// 한쪽이라도 “관심 Block”이면 해당 가상 포트를 생성해야 합니다.
-		if false && !srcIsPort && !dstIsPort {
+		// 한쪽이라도 “관심 Block”이면 해당 가상 포트를 생성해야 합니다(레벨 규칙의 virtual_ports가 켜진 경우에만).
+		if virtualPorts && !srcIsPort && !dstIsPort {
 			srcBlk, ok1 := blocksBySID[srcSID]
 			dstBlk, ok2 := blocksBySID[dstSID]
 			if !ok1 || !ok2 {
@@ -306,6 +391,13 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 			srcName := normalizeName(srcBlk.Name)
 			dstName := normalizeName(dstBlk.Name)
 
+			// 가상 포트의 종류: 제어 입력이면 그 종류, BusCreator/BusSelector와 연결되면 버스 요소, 그 외에는 데이터
+			baseKind := Public_data.M1PortKindData
+			if srcBlk.BlockType == "BusCreator" || dstBlk.BlockType == "BusSelector" {
+				baseKind = Public_data.M1PortKindBusElement
+			}
+			kind := kinds.edgeKind(e, baseKind)
+
 			// 연결 이름 생성: 여러 선이면 _1/_2 접미사를 붙입니다.
 			label := ""
 			if total > 1 {
@@ -331,6 +423,7 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 						Level:     level,
 						BlockType: "Outport",
 						PortType:  "S-R",
+						Kind:      kind,
 						Virtual:   true,
 					}
 				}
@@ -355,6 +448,7 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 						Level:     level,
 						BlockType: "Inport",
 						PortType:  "S-R",
+						Kind:      kind,
 						Virtual:   true,
 					}
 				}
@@ -367,47 +461,29 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 		}
 	}
 
-	// 5）통일하여 “Block → Ports” 순서로 txt에 출력합니다.
-	if M1_Public_Data.TxtDir == "" || modelName == "" {
-		// 출력 디렉터리가 없으면 바로 종료합니다.
-		return nil
-	}
-
-	txtPath := filepath.Join(M1_Public_Data.TxtDir, modelName+".txt")
-	f, err := os.OpenFile(txtPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return fmt.Errorf("txt 파일에 쓸 수 없습니다. [%s]: %w", txtPath, err)
-	}
-	defer f.Close()
-
+	// 5）통일하여 “Block → Ports” 순서로 res.Blocks에 추가합니다.
+	added := 0
 	for _, sid := range blockOrder {
 		blk, ok := blocksBySID[sid]
 		if !ok {
 			continue
 		}
 
-		name := normalizeName(blk.Name)
-
-		// 먼저 Block 자체 정보를 출력하고, FatherNode를 함께 표시합니다(2층부터).
-		var blockLine string
-		if fatherName != "" && level >= 2 {
-			blockLine = fmt.Sprintf(
-				"[L%d] Name: %-10s\tBlockType=%-10s\tSID=%-10s\tFatherNode=%-10s\n",
-				level, name, blk.BlockType, blk.SID, fatherName,
-			)
-		} else {
-			blockLine = fmt.Sprintf(
-				"[L%d] Name: %s\tBlockType=%s\tSID=%s\n",
-				level, name, blk.BlockType, blk.SID,
-			)
+		// 먼저 Block 자체 정보를 기록하고, FatherNode를 함께 기록합니다(2층부터).
+		block := &M1_Public_Data.Block{
+			Level:     level,
+			Name:      normalizeName(blk.Name),
+			SID:       blk.SID,
+			BlockType: blk.BlockType,
+			Path:      M1_Public_Data.JoinSIDPath(fatherPath, blk.SID),
 		}
-
-		if _, err := f.WriteString(blockLine); err != nil {
-			return err
+		if level >= 2 {
+			block.Father = fatherName
+			block.FatherPath = fatherPath
 		}
 
-		// Block 행 바로 뒤에 Connect를 출력( L2+ 이고 현재 블록이 SubSystem인 경우에만 )
-		if level >= 2 && blk.BlockType == "SubSystem" {
+		// Block 바로 뒤에 Connect를 기록( L2+ 이고 현재 블록이 SubSystem인 경우에만 )
+		if level >= 2 && M1_Public_Data.IsSubSystemLike(blk.BlockType) {
 			if conns, ok := subsysConnect[sid]; ok && len(conns) > 0 {
 				var items []connectItem
 				for dstSID, strength := range conns {
@@ -422,7 +498,7 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 					})
 				}
 
-				// 출력 정렬: 대상 SubSystem 이름 기준으로 정렬하여 안정성을 보장
+				// 정렬: 대상 SubSystem 이름 기준으로 정렬하여 안정성을 보장
 				sort.Slice(items, func(i, j int) bool {
 					if items[i].DstName == items[j].DstName {
 						return items[i].DstSID < items[j].DstSID
@@ -431,74 +507,172 @@ func AnalyzePortsInFile(dir, file string, level int, modelName, fatherName strin
 				})
 
 				for _, it := range items {
-					line := fmt.Sprintf(
-						"\t[L%d Connect] Name:%-40s\tSID=%-10s\tstrength=%d\n",
-						level, it.DstName, it.DstSID, it.Strength,
-					)
-					if _, err := f.WriteString(line); err != nil {
-						return err
-					}
+					block.Connects = append(block.Connects, M1_Public_Data.Connect{
+						Name:     it.DstName,
+						SID:      it.DstSID,
+						Strength: it.Strength,
+					})
 				}
 			}
 		}
 
-		// 그다음 이 Block의 모든 Port/의사 포트 정보를 출력합니다.
+		// 그다음 이 Block의 모든 Port/의사 포트 정보를 기록합니다.
 		if ports, ok := blockToPorts[sid]; ok {
 			for _, psid := range ports {
 				pinfo, ok := portInfos[psid]
 				if !ok {
 					continue
 				}
-
-				// 가상 포트 여부에 따라 다른 태그를 선택합니다.
-				label := "Port"
-				if pinfo.Virtual {
-					label = "virtual Port"
-				}
-
-				// L1에서만 PortType을 출력하고, L2 및 이후에는 PortType을 출력하지 않습니다.
-				var portLine string
-				if level == 1 {
-					portLine = fmt.Sprintf(
-						"\t[L%d %s] Name: %-40s\tBlockType=%-10s\tSID=%-10s\tPortType=%-10s\n",
-						level, label, pinfo.Name, pinfo.BlockType, pinfo.SID, pinfo.PortType,
-					)
-				} else {
-					portLine = fmt.Sprintf(
-						"\t[L%d %s] Name:%-40s\tBlockType=%-10s\tSID=%-10s\n",
-						level, label, pinfo.Name, pinfo.BlockType, pinfo.SID,
-					)
-				}
-
-				if _, err := f.WriteString(portLine); err != nil {
-					return err
+				kind := pinfo.Kind
+				if k, ok := attachedKinds[sid][psid]; ok {
+					kind = k
 				}
+				block.Ports = append(block.Ports, M1_Public_Data.Port{
+					Name:      pinfo.Name,
+					SID:       pinfo.SID,
+					BlockType: pinfo.BlockType,
+					PortType:  pinfo.PortType,
+					Kind:      kind,
+					Virtual:   pinfo.Virtual,
+				})
 			}
 		}
+
+		res.Blocks = append(res.Blocks, block)
+		added++
 	}
 
-	// 6）L1에서 C-S 포트를 추가합니다(BuildDir\<Model>\simulink\graphicalInterface.xml에서 가져옴).
-	if level == 1 {
-		csPorts, err := C_S_Analysis.GetCSPorts(modelName)
+	// 6）C-S 포트를 추가합니다(<Model>.slx 내부의 simulink/graphicalInterface.xml에서 가져옴).
+	// 각 포트는 구현 블록(Function Caller / Simulink Function)을 포함하는 노드에 붙이며, 구현 블록의 SID를 사용합니다.
+	// L1에서 모델의 C-S 포트를 읽어 res.CSPorts에 두고, L2 이후에도 같은 모델(참조 모델 제외)의 노드에 반영합니다.
+	newBlocks := res.Blocks[len(res.Blocks)-added:]
+	if level == 1 && added > 0 {
+		csPorts, source, err := C_S_Analysis.GetCSPorts(model)
 		if err != nil {
 			// 전체 흐름을 중단하지 않고, 안내만 합니다.
-			fmt.Printf("⚠️ C-S 포트 파싱에 실패했습니다：%v\n", err)
-		} else if len(csPorts) > 0 {
-			for _, p := range csPorts {
-				line := fmt.Sprintf(
-					"\t[L1 Port] Name: %-40s\tBlockType=%-10s\tSID=%-10s\tPortType=%-10s\n",
-					p.Name, p.BlockType, p.SID, p.PortType,
-				)
-				if _, err := f.WriteString(line); err != nil {
-					return err
-				}
+			res.Logf("⚠️ C-S 포트 파싱에 실패했습니다：%v\n", err)
+		} else {
+			res.Logf("ℹ️ C-S 포트 %d개 (%s)\n", len(csPorts), path.Base(source))
+		}
+		res.CSPorts = csPorts
+
+		// 구현 블록을 찾지 못한 포트는 모델 단위의 포트로 보고, 이 레이어에서 마지막으로 추가한 L1 블록에 붙입니다
+		// (기존 txt 형식에서 C-S 포트가 L1 블록 목록 뒤에 오던 것과 같은 결과).
+		last := newBlocks[len(newBlocks)-1]
+		for _, p := range csPorts {
+			if !attachCSPort(newBlocks, p) {
+				last.Ports = append(last.Ports, p.Port())
 			}
 		}
+	} else if level >= 2 && model.Name() == res.Name {
+		for _, p := range res.CSPorts {
+			attachCSPort(newBlocks, p)
+		}
 	}
 
 	return nil
 }
 
+// isPortBlockType은 블록이 아니라 포트를 나타내는 BlockType인지 반환합니다.
+func isPortBlockType(blockType string) bool {
+	for _, t := range Public_data.M1PortBlockTypes {
+		if t == blockType {
+			return true
+		}
+	}
+	return false
+}
+
+// portBlockKind는 포트 블록 자체의 설정으로 정해지는 포트 종류입니다.
+//   - IsBusElementPort=on(In/Out Bus Element): 버스 요소
+//   - OutputFunctionCall=on인 Inport: 함수 호출
+func portBlockKind(b xmlBlock) string {
+	switch {
+	case b.property("IsBusElementPort") == "on":
+		return Public_data.M1PortKindBusElement
+	case b.BlockType == "Inport" && b.property("OutputFunctionCall") == "on":
+		return Public_data.M1PortKindFunctionCall
+	}
+	return Public_data.M1PortKindData
+}
+
+// isControlInput은 대상 블록의 입력이 제어 입력(trigger/enable/ifaction)인지 반환합니다.
+func isControlInput(port string) bool {
+	switch port {
+	case "trigger", "enable", "ifaction":
+		return true
+	}
+	return false
+}
+
+// kindResolver는 선이 들어가는 대상 블록의 입력으로 포트 종류를 정합니다.
+// trigger 입력은 대상 SubSystem 내부의 TriggerPort가 function-call이면 함수 호출로 보며, 그 결과를 블록별로 기억합니다.
+type kindResolver struct {
+	model        Model_Reader.Model
+	functionCall map[string]bool // 대상 블록 SID → 내부 TriggerPort가 function-call인지
+}
+
+func newKindResolver(model Model_Reader.Model) *kindResolver {
+	return &kindResolver{model: model, functionCall: make(map[string]bool)}
+}
+
+// edgeKind는 e가 대상 블록의 제어 입력(trigger/enable/ifaction)으로 들어가면 그 종류를, 아니면 base를 반환합니다.
+func (k *kindResolver) edgeKind(e Connection_Analysis.Edge, base string) string {
+	switch e.Dst.Kind {
+	case "trigger":
+		if k.isFunctionCallTrigger(e.Dst.SID) {
+			return Public_data.M1PortKindFunctionCall
+		}
+		return Public_data.M1PortKindTrigger
+	case "enable":
+		return Public_data.M1PortKindEnable
+	case "ifaction":
+		return Public_data.M1PortKindAction
+	}
+	return base
+}
+
+// isFunctionCallTrigger는 같은 모델의 system_<sid>.xml에 TriggerType=function-call인 TriggerPort가 있는지 반환합니다.
+// 하위 system 파일이 없거나(라이브러리 링크/참조 모델) 읽을 수 없으면 일반 트리거로 봅니다.
+func (k *kindResolver) isFunctionCallTrigger(sid string) bool {
+	if v, ok := k.functionCall[sid]; ok {
+		return v
+	}
+	result := false
+	name := Model_Reader.SystemPath(Model_Reader.SystemFile(sid))
+	if k.model.Exists(name) {
+		if data, err := k.model.ReadFile(name); err == nil {
+			var sys xmlSystem
+			if xml.Unmarshal(data, &sys) == nil {
+				for _, b := range sys.Blocks {
+					if b.BlockType == "TriggerPort" && b.property("TriggerType") == "function-call" {
+						result = true
+						break
+					}
+				}
+			}
+		}
+	}
+	k.functionCall[sid] = result
+	return result
+}
+
+// attachCSPort는 C-S 포트를 구현 블록을 포함하는(또는 구현 블록 자체인) 노드에 붙이고, 붙인 노드가 있는지 반환합니다.
+func attachCSPort(blocks []*M1_Public_Data.Block, p M1_Public_Data.CSPort) bool {
+	attached := false
+	for _, blk := range blocks {
+		impl, ok := p.ImplementedUnder(blk.Path)
+		if !ok {
+			continue
+		}
+		port := p.Port()
+		port.SID = impl.SID
+		blk.Ports = append(blk.Ports, port)
+		attached = true
+	}
+	return attached
+}
+
 // 이름에 있는 줄바꿈/불필요한 공백을 하나의 공백으로 압축합니다.
 func normalizeName(s string) string {
 	s = strings.TrimSpace(s)
@@ -507,3 +681,91 @@ func normalizeName(s string) string {
 	}
 	return strings.Join(strings.Fields(s), " ")
 }
+
+// BlockPorts는 블록 하나와 그 블록에서 선이 연결될 수 있는 포트(끝점) 목록입니다.
+type BlockPorts struct {
+	SID       string
+	Name      string
+	BlockType string
+	Ports     []Connection_Analysis.Endpoint // in:1..N, out:1..M, enable, trigger, ifaction, reset
+}
+
+// portsParamKinds는 블록의 Ports 파라미터([in, out, enable, trigger, state, lconn, rconn, ifaction, reset])의
+// 위치별 포트 종류입니다. state와 물리 연결(lconn/rconn)은 신호선이 아니므로 세지 않습니다(빈 문자열).
+var portsParamKinds = []string{"in", "out", "enable", "trigger", "", "", "", "ifaction", "reset"}
+
+// defaultPortsParam은 Ports 파라미터를 생략하는 블록의 기본 포트 수([in, out])입니다.
+var defaultPortsParam = map[string][]int{
+	"Inport":       {0, 1},
+	"InportShadow": {0, 1},
+	"Outport":      {1, 0},
+	"Constant":     {0, 1},
+	"Ground":       {0, 1},
+	"Terminator":   {1, 0},
+	"From":         {0, 1},
+	"Goto":         {1, 0},
+}
+
+// BlockPortsInFile은 system_xxx.xml의 블록별 포트 목록을 반환합니다.
+// 포트 수는 Ports 파라미터(없으면 BlockType별 기본값)에서 가져오며, 알 수 없는 블록의 Ports는 비어 있습니다.
+func BlockPortsInFile(model Model_Reader.Model, file string) ([]BlockPorts, error) {
+	fullPath := model.Location(Model_Reader.SystemPath(file))
+
+	data, err := model.ReadFile(Model_Reader.SystemPath(file))
+	if err != nil {
+		return nil, fmt.Errorf("XML 읽기 실패 [%s]: %w", fullPath, err)
+	}
+
+	var sys xmlSystem
+	if err := xml.Unmarshal(data, &sys); err != nil {
+		return nil, fmt.Errorf("XML 파싱 실패 [%s]: %w", fullPath, err)
+	}
+
+	list := make([]BlockPorts, 0, len(sys.Blocks))
+	for _, b := range sys.Blocks {
+		bp := BlockPorts{SID: b.SID, Name: normalizeName(b.Name), BlockType: b.BlockType}
+
+		counts, ok := parsePortsParam(b.property("Ports"))
+		if !ok {
+			counts = defaultPortsParam[b.BlockType]
+		}
+		for i, c := range counts {
+			if i >= len(portsParamKinds) || portsParamKinds[i] == "" {
+				continue
+			}
+			kind := portsParamKinds[i]
+			for n := 1; n <= c; n++ {
+				ep := Connection_Analysis.Endpoint{SID: b.SID, Kind: kind}
+				// 입출력 포트만 번호가 있고, enable/trigger 등은 블록마다 하나입니다.
+				if kind == "in" || kind == "out" {
+					ep.Index = n
+				}
+				bp.Ports = append(bp.Ports, ep)
+			}
+		}
+		list = append(list, bp)
+	}
+	return list, nil
+}
+
+// parsePortsParam은 "[5, 1]" / "[0, 0, 0, 1]" 형식의 Ports 파라미터를 정수 목록으로 바꿉니다.
+// 파라미터가 없거나 형식이 다르면 ok=false입니다("[]"는 포트가 없는 블록).
+func parsePortsParam(v string) ([]int, bool) {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "[") || !strings.HasSuffix(v, "]") {
+		return nil, false
+	}
+	v = strings.TrimSpace(v[1 : len(v)-1])
+	if v == "" {
+		return nil, true
+	}
+	var counts []int
+	for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, false
+		}
+		counts = append(counts, n)
+	}
+	return counts, true
+}
@@ -0,0 +1,100 @@
+package Port_Analysis
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Reader"
+	"FCU_Tools/Public_data"
+)
+
+// memModel은 내부 파일을 메모리에 담은 테스트용 Model입니다.
+type memModel struct {
+	name  string
+	files map[string]string
+}
+
+func (m *memModel) Name() string { return m.name }
+
+func (m *memModel) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", name, os.ErrNotExist)
+	}
+	return []byte(data), nil
+}
+
+func (m *memModel) Exists(name string) bool {
+	_, ok := m.files[name]
+	return ok
+}
+
+func (m *memModel) Location(name string) string { return m.name + "!" + name }
+func (m *memModel) Close() error                { return nil }
+
+// 두 SubSystem이 블록-블록 선 두 개(66#out:1 → 69#in:3, 66#out:2 → 69#in:4)로 직접 연결된 system입니다.
+const parallelLinesSystem = `<?xml version="1.0" encoding="utf-8"?>
+<System>
+  <Block BlockType="SubSystem" Name="A" SID="66"/>
+  <Block BlockType="SubSystem" Name="B" SID="69"/>
+  <Line>
+    <P Name="Src">66#out:1</P>
+    <P Name="Dst">69#in:3</P>
+  </Line>
+  <Line>
+    <P Name="Src">66#out:2</P>
+    <P Name="Dst">69#in:4</P>
+  </Line>
+</System>`
+
+func analyzeParallelLines(t *testing.T, virtualPorts bool) map[string]*M1_Public_Data.Block {
+	t.Helper()
+	model := &memModel{
+		name:  "M",
+		files: map[string]string{Model_Reader.SystemPath("system_1.xml"): parallelLinesSystem},
+	}
+	res := &M1_Public_Data.ModelResult{Name: "M"}
+	if err := AnalyzePortsInFile(res, model, "system_1.xml", 2, virtualPorts, "Top", "1", []string{"66", "69"}); err != nil {
+		t.Fatalf("AnalyzePortsInFile: %v", err)
+	}
+	blocks := make(map[string]*M1_Public_Data.Block)
+	for _, b := range res.Blocks {
+		blocks[b.SID] = b
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("블록 수 = %d, want 2", len(blocks))
+	}
+	return blocks
+}
+
+func TestAnalyzePortsInFileVirtualPorts(t *testing.T) {
+	blocks := analyzeParallelLines(t, true)
+
+	check := func(sid, blockType string) {
+		t.Helper()
+		ports := blocks[sid].Ports
+		if len(ports) != 2 {
+			t.Fatalf("블록 %s의 포트 수 = %d, want 2: %+v", sid, len(ports), ports)
+		}
+		for i, p := range ports {
+			wantName := fmt.Sprintf("A->B_%d", i+1)
+			if p.Name != wantName || p.SID != "66->69" || p.BlockType != blockType || !p.Virtual ||
+				p.PortType != "S-R" || p.Kind != Public_data.M1PortKindData {
+				t.Errorf("블록 %s 포트 %d = %+v, want Name=%s SID=66->69 BlockType=%s Virtual Kind=data", sid, i, p, wantName, blockType)
+			}
+		}
+	}
+	check("66", "Outport")
+	check("69", "Inport")
+}
+
+func TestAnalyzePortsInFileNoVirtualPorts(t *testing.T) {
+	blocks := analyzeParallelLines(t, false)
+	for sid, b := range blocks {
+		if len(b.Ports) != 0 {
+			t.Errorf("virtualPorts=false인데 블록 %s에 포트가 있습니다: %+v", sid, b.Ports)
+		}
+	}
+}
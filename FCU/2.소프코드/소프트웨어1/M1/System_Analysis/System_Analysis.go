@@ -3,11 +3,12 @@ package System_Analysis
 import (
 	"encoding/xml"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_Reader"
 	"FCU_Tools/M1/Port_Analysis"
+	"FCU_Tools/Public_data"
 )
 
 // SubSystem의 Name/SID/Level/BlockType을 저장하는 데 사용됩니다.
@@ -16,6 +17,11 @@ type SubSystemInfo struct {
 	SID       string
 	Level     int
 	BlockType string
+
+	Masked         bool   // 마스크가 적용된 블록
+	Atomic         bool   // TreatAsAtomicUnit=on인 SubSystem
+	LibraryLink    string // BlockType=Reference: 라이브러리 블록 경로(SourceBlock, 예: "MyLib/Filter")
+	ModelReference string // BlockType=ModelReference: 참조 모델 이름
 }
 
 // P 태그
@@ -38,6 +44,17 @@ type xmlBlock struct {
 	SID        string         `xml:"SID,attr"`
 	PortCounts *xmlPortCounts `xml:"PortCounts"`
 	Properties []xmlP         `xml:"P"`
+	Mask       *struct{}      `xml:"Mask"`
+}
+
+// property는 이름에 해당하는 P 태그의 값을 반환합니다(없으면 빈 문자열).
+func (b xmlBlock) property(name string) string {
+	for _, p := range b.Properties {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
 }
 
 type xmlSystem struct {
@@ -45,43 +62,18 @@ type xmlSystem struct {
 }
 
 // ======================== 외부 입력 포트 ================================
+// rule: 이 레벨에 적용할 규칙(노드로 셀 BlockType, 빈 포트 필터 적용 여부)
 // fatherName: 현재 system_xxx.xml에 해당하는 부모 노드 이름(L1은 빈 문자열)
-func AnalyzeSubSystemsInFile(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	switch level {
-	case 1:
-		return analyzeSubSystemsLevel1(dir, file, level, fatherName)
-	case 2:
-		return analyzeSubSystemsLevel2(dir, file, level, fatherName)
-	case 3:
-		return analyzeSubSystemsLevel3(dir, file, level, fatherName)
-	default:
-		// 3층 및 이후는 모두 “Inport/Outport가 아닌 Block”으로 통일하여 처리합니다.
-		return analyzeSubSystemsLevel3(dir, file, level, fatherName)
-	}
-}
-//여기서는 원래 L1과 L2 레이어를 두 개의 함수로 각각 분석해야 하지만, 분석 함수 안에서 이미 구분 로직이 있으므로 L1과 L2는 동일한 함수를 사용합니다.
-// ======================== 로직 1(L1: 유효하지 않은 SubSystem 필터링) ================================
-func analyzeSubSystemsLevel1(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	return analyzeSubSystemsCommon(dir, file, level, true, fatherName)
-}
+// fatherPath: 부모 노드의 SID 경로(L1은 빈 문자열). 같은 이름의 노드를 구분하는 데 사용합니다.
+//
+// 기본 규칙(Public_data.DefaultM1LevelRules)에서는 L1: 유효한 SubSystem, L2: 모든 SubSystem,
+// L3 이후: 포트 블록(Inport/Outport/InportShadow/TriggerPort 등)이 아닌 모든 Block을 노드로 셉니다.
+func AnalyzeSubSystemsInFile(res *M1_Public_Data.ModelResult, model Model_Reader.Model, file string, level int, rule Public_data.M1LevelRule, fatherName, fatherPath string) ([]SubSystemInfo, error) {
 
-// ======================== 로직 2(L2: SubSystem을 필터링하지 않음) ================================
-func analyzeSubSystemsLevel2(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	return analyzeSubSystemsCommon(dir, file, level, false, fatherName)
-}
-
-// ======================== 로직 3(L3+: Inport/Outport가 아닌 Block) =========================
-func analyzeSubSystemsLevel3(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	return analyzeNonPortBlocks(dir, file, level, fatherName)
-}
-
-// ======================== 범용 SubSystem 분석(재귀 제거, 외부에서 제어) ====================
-func analyzeSubSystemsCommon(dir, file string, level int, applyLevel1Filter bool, fatherName string) ([]SubSystemInfo, error) {
-	
-	//분석할 파일의 경로를 조합합니다.
-	fullPath := filepath.Join(dir, file)
+	//분석할 파일의 위치(오류 메시지용)
+	fullPath := model.Location(Model_Reader.SystemPath(file))
 
-	data, err := os.ReadFile(fullPath)
+	data, err := model.ReadFile(Model_Reader.SystemPath(file))
 	if err != nil {
 		return nil, fmt.Errorf("XML 읽기 실패 [%s]: %w", fullPath, err)
 	}
@@ -91,44 +83,18 @@ func analyzeSubSystemsCommon(dir, file string, level int, applyLevel1Filter bool
 		return nil, fmt.Errorf("XML 파싱 실패 [%s]: %w", fullPath, err)
 	}
 
-	// 모델명을 추출합니다: BuildDir/<Model>/simulink/systems → <Model>
-	modelDir := filepath.Dir(filepath.Dir(dir))
-	modelName := filepath.Base(modelDir)
-
 	var result []SubSystemInfo
 	var blockSIDs []string
 
 	for _, b := range sys.Blocks {
 
-		if b.BlockType != "SubSystem" {
+		if !rule.CountsBlockType(b.BlockType) {
 			continue
 		}
 
-		// === level=1일 때 필터링: Ports가 비어 있거나 PortCounts가 비어 있는 SubSystem은 바로 건너뜁니다 ===
-		if applyLevel1Filter && level == 1 {
-			invalid := false
-			// (1)과 (2) 중 하나라도 충족하지 못하면 부적격 블록이며, 즉 1층에서는 초기화된 SubSystem이 존재하므로 필터링해야 합니다.
-			// (1) Ports = []
-			for _, p := range b.Properties {
-				if p.Name == "Ports" {
-					v := strings.TrimSpace(p.Value)
-					if v == "[]" || v == "" {
-						invalid = true
-						break
-					}
-				}
-			}
-
-			// (2) PortCounts 태그가 존재하지만 비어 있습니다.
-			if !invalid && b.PortCounts != nil {
-				if b.PortCounts.In == "" && b.PortCounts.Out == "" && b.PortCounts.Trigger == "" {
-					invalid = true
-				}
-			}
-
-			if invalid {
-				continue
-			}
+		// === 규칙에 따라 필터링: Ports가 비어 있거나 PortCounts가 비어 있는 블록은 바로 건너뜁니다 ===
+		if rule.SkipEmptyPorts && hasEmptyPorts(b) {
+			continue
 		}
 
 		// 이름을 한 번 정리하여 줄바꿈과 여러 공백을 제거합니다.
@@ -139,72 +105,138 @@ func analyzeSubSystemsCommon(dir, file string, level int, applyLevel1Filter bool
 			Name:      name,
 			SID:       b.SID,
 			Level:     level,
-			BlockType: b.BlockType, // "SubSystem"
+			BlockType: b.BlockType,
+			Masked:    b.Mask != nil || b.property("Mask") == "on",
+			Atomic:    b.property("TreatAsAtomicUnit") == "on",
+		}
+		switch b.BlockType {
+		case "Reference":
+			info.LibraryLink = b.property("SourceBlock")
+		case "ModelReference":
+			info.ModelReference = modelReferenceName(b)
 		}
 		result = append(result, info)
 		blockSIDs = append(blockSIDs, b.SID)
 	}
 
 	// 이 레이어에서 출력할 BlockSID 목록을 Port_Analysis에 전달하여, 그쪽에서 Block → Port 순서로 통일해 출력하도록 합니다.
-	if len(blockSIDs) > 0 && modelName != "" {
-		if err := Port_Analysis.AnalyzePortsInFile(dir, file, level, modelName, fatherName, blockSIDs); err != nil {
-			fmt.Printf("⚠️ Port_Analysis 분석 실패 [%s]: %v\n", fullPath, err)
+	if len(blockSIDs) > 0 && model.Name() != "" {
+		added := len(res.Blocks)
+		if err := Port_Analysis.AnalyzePortsInFile(res, model, file, level, rule.VirtualPorts, fatherName, fatherPath, blockSIDs); err != nil {
+			return nil, fmt.Errorf("Port_Analysis 분석 실패 [%s]: %w", fullPath, err)
 		}
+		markBlocks(res.Blocks[added:], result)
 	}
 
 	return result, nil
 }
 
-// ======================== Inport/Outport가 아닌 Block 분석(3층 및 이후) ==================
-// 지정된 system_xxx.xml에서 BlockType이 "Inport"가 아니고 "Outport"도 아닌 모든 Block을 찾습니다.
-// 이들 Block의 Name/BlockType/SID를 기록하고, Port_Analysis에 전달해 통일 출력합니다.
-func analyzeNonPortBlocks(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	fullPath := filepath.Join(dir, file)
-
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return nil, fmt.Errorf("XML 읽기 실패 [%s]: %w", fullPath, err)
+// markBlocks는 Port_Analysis가 이번에 추가한 Block에 마스크/원자/참조 정보를 기록합니다.
+func markBlocks(blocks []*M1_Public_Data.Block, infos []SubSystemInfo) {
+	bySID := make(map[string]SubSystemInfo, len(infos))
+	for _, info := range infos {
+		bySID[info.SID] = info
 	}
-
-	var sys xmlSystem
-	if err := xml.Unmarshal(data, &sys); err != nil {
-		return nil, fmt.Errorf("XML 파싱 실패 [%s]: %w", fullPath, err)
+	for _, blk := range blocks {
+		info, ok := bySID[blk.SID]
+		if !ok {
+			continue
+		}
+		blk.Masked = info.Masked
+		blk.Atomic = info.Atomic
+		blk.LibraryLink = info.LibraryLink
+		blk.ModelReference = info.ModelReference
 	}
+}
 
-	// 모델명을 추출합니다: BuildDir/<Model>/simulink/systems → <Model>
-	modelDir := filepath.Dir(filepath.Dir(dir))
-	modelName := filepath.Base(modelDir)
-
-	var result []SubSystemInfo
-	var blockSIDs []string
-
-	for _, b := range sys.Blocks {
+// modelReferenceName은 ModelReference 블록이 가리키는 모델 이름을 확장자 없이 반환합니다.
+func modelReferenceName(b xmlBlock) string {
+	name := b.property("ModelName")
+	if name == "" {
+		name = b.property("ModelNameDialog")
+	}
+	for _, ext := range []string{".slx", ".slxp", ".mdl"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
 
-		// Inport와 Outport는 건너뜁니다.
-		if b.BlockType == "Inport" || b.BlockType == "Outport" {
+// SplitSourceBlock은 라이브러리 블록 경로(SourceBlock)를 라이브러리 이름과 라이브러리 안의 블록 이름 목록으로 나눕니다.
+// 블록 이름에 포함된 "/"는 Simulink 규칙에 따라 "//"로 표기되어 있습니다. 예: "MyLib/A//B/C" → "MyLib", ["A/B", "C"]
+func SplitSourceBlock(src string) (string, []string) {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(src); i++ {
+		if src[i] != '/' {
+			cur.WriteByte(src[i])
 			continue
 		}
+		if i+1 < len(src) && src[i+1] == '/' {
+			cur.WriteByte('/')
+			i++
+			continue
+		}
+		parts = append(parts, cur.String())
+		cur.Reset()
+	}
+	parts = append(parts, cur.String())
+	return parts[0], parts[1:]
+}
 
-		rawName := strings.TrimSpace(b.Name)
-		name := strings.Join(strings.Fields(rawName), " ")
+// ResolveLibraryBlock은 라이브러리 모델 lib에서 blockPath(라이브러리 최상위부터의 블록 이름)를 따라가
+// 해당 블록의 내용이 들어 있는 system_<SID>.xml 파일 이름을 반환합니다.
+func ResolveLibraryBlock(lib Model_Reader.Model, blockPath []string) (string, error) {
+	if len(blockPath) == 0 {
+		return "", fmt.Errorf("라이브러리 블록 경로가 비어 있습니다 [%s]", lib.Name())
+	}
 
-		info := SubSystemInfo{
-			Name:      name,
-			SID:       b.SID,
-			Level:     level,
-			BlockType: b.BlockType,
+	file := Model_Reader.RootSystemFile
+	for _, seg := range blockPath {
+		data, err := lib.ReadFile(Model_Reader.SystemPath(file))
+		if err != nil {
+			return "", fmt.Errorf("XML 읽기 실패 [%s]: %w", lib.Location(Model_Reader.SystemPath(file)), err)
+		}
+		var sys xmlSystem
+		if err := xml.Unmarshal(data, &sys); err != nil {
+			return "", fmt.Errorf("XML 파싱 실패 [%s]: %w", lib.Location(Model_Reader.SystemPath(file)), err)
 		}
 
-		result = append(result, info)
-		blockSIDs = append(blockSIDs, b.SID)
+		want := strings.Join(strings.Fields(seg), " ")
+		sid := ""
+		for _, b := range sys.Blocks {
+			if strings.Join(strings.Fields(b.Name), " ") == want {
+				sid = b.SID
+				break
+			}
+		}
+		if sid == "" {
+			return "", fmt.Errorf("라이브러리 [%s]에서 블록 %q를 찾을 수 없습니다", lib.Name(), strings.Join(blockPath, "/"))
+		}
+		file = Model_Reader.SystemFile(sid)
 	}
+	return file, nil
+}
 
-	// Port_Analysis에 넘겨 Block + Port를 통일된 형식으로 출력합니다.
-	if len(blockSIDs) > 0 && modelName != "" {
-		if err := Port_Analysis.AnalyzePortsInFile(dir, file, level, modelName, fatherName, blockSIDs); err != nil {
-			fmt.Printf("⚠️ Port_Analysis 분석 실패 [%s]: %v\n", fullPath, err)
+// hasEmptyPorts는 블록이 초기화된(포트가 없는) 블록인지 반환합니다.
+// (1)과 (2) 중 하나라도 해당하면 부적격 블록이며, 예를 들어 1층에는 초기화된 SubSystem이 존재하므로 필터링해야 합니다.
+func hasEmptyPorts(b xmlBlock) bool {
+	// (1) Ports = []
+	for _, p := range b.Properties {
+		if p.Name == "Ports" {
+			v := strings.TrimSpace(p.Value)
+			if v == "[]" || v == "" {
+				return true
+			}
 		}
 	}
 
-	return result, nil
+	// (2) PortCounts 태그가 존재하지만 비어 있습니다.
+	if b.PortCounts != nil {
+		if b.PortCounts.In == "" && b.PortCounts.Out == "" && b.PortCounts.Trigger == "" {
+			return true
+		}
+	}
+	return false
 }
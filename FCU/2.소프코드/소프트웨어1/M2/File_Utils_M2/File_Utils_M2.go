@@ -3,7 +3,6 @@ package File_Utils_M2
 import (
 	"encoding/csv"
 	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -11,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/Public_data"
 )
 
@@ -41,16 +41,10 @@ import (
 // PrepareM2OutputDir는 M2의 출력 디렉터리를 준비합니다.
 //
 // 절차:
-//   1) 현재 작업 디렉터리를 가져옵니다.
-//   2) <작업 디렉터리>/M2/output 경로를 조합합니다.
-//   3) output/이 이미 존재하면 먼저 삭제한 뒤 새로 생성합니다.
-//   4) 해당 경로를 Public_data.M2OutputlPath에 저장합니다.
-func PrepareM2OutputDir() error {
-	basePath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("작업 디렉토리를 가져오지 못했습니다: %v", err)
-	}
-	outputPath := filepath.Join(basePath, "M2", "output")
+//   1) cfg.MetricOutputDir("M2")로 <WorkDir>/M2/output 경로를 얻습니다.
+//   2) output/이 이미 존재하면 먼저 삭제한 뒤 새로 생성합니다.
+func PrepareM2OutputDir(cfg *Public_data.RunConfig) error {
+	outputPath := cfg.MetricOutputDir("M2")
 
 	if _, err := os.Stat(outputPath); err == nil {
 		if err := os.RemoveAll(outputPath); err != nil {
@@ -62,9 +56,6 @@ func PrepareM2OutputDir() error {
 		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다: %v", err)
 	}
 
-	// Public_data에 경로 저장 변수
-	Public_data.M2OutputlPath = outputPath
-
 	return nil
 }
 
@@ -76,22 +67,22 @@ func PrepareM2OutputDir() error {
 //   3) 정규식을 이용해 JSON key의 접두어([REQ] 형태)를 매칭하고,
 //      excelMap을 활용해 컴포넌트명으로 매핑.
 //
-func GenerateM2LDIXml() error {
+func GenerateM2LDIXml(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
 	// complexity.json 읽기
-	data, err := ioutil.ReadFile(Public_data.M2ComplexityJsonPath)
+	data, err := ioutil.ReadFile(cfg.M2ComplexityJsonPath)
 	if err != nil {
-		return fmt.Errorf("complexity.json 읽기 실패: %v", err)
+		return nil, fmt.Errorf("complexity.json 읽기 실패: %v", err)
 	}
 
 	var jsonMap map[string]float64
 	if err := json.Unmarshal(data, &jsonMap); err != nil {
-		return fmt.Errorf("complexity.json 살펴보기 실패: %v", err)
+		return nil, fmt.Errorf("complexity.json 살펴보기 실패: %v", err)
 	}
 
 	// CSV 파일 열기 (rq_versus_component.csv)
-	f, err := os.Open(Public_data.M2RqExcelPath)
+	f, err := os.Open(cfg.M2RqExcelPath)
 	if err != nil {
-		return fmt.Errorf("CSV 열기 실패: %v", err)
+		return nil, fmt.Errorf("CSV 열기 실패: %v", err)
 	}
 	defer f.Close()
 
@@ -101,7 +92,7 @@ func GenerateM2LDIXml() error {
 
 	excelRows, err := r.ReadAll()
 	if err != nil {
-		return fmt.Errorf("CSV 행 읽기 실패: %v", err)
+		return nil, fmt.Errorf("CSV 행 읽기 실패: %v", err)
 	}
 
 	excelMap := make(map[string]string)
@@ -111,47 +102,20 @@ func GenerateM2LDIXml() error {
 		}
 	}
 
-	type Property struct {
-		XMLName xml.Name `xml:"property"`
-		Name    string   `xml:"name,attr"`
-		Value   string   `xml:",chardata"`
-	}
-	type Element struct {
-		XMLName  xml.Name  `xml:"element"`
-		Name     string    `xml:"name,attr"`
-		Property []Property `xml:"property"`
-	}
-	type Root struct {
-		XMLName xml.Name  `xml:"ldi"`
-		Items   []Element `xml:"element"`
-	}
-
-	var result Root
+	result := LDI_Model.New()
 	re := regexp.MustCompile(`^\[[^\]]+\]`)
 	for key, val := range jsonMap {
 		match := re.FindString(key)
 		if compName, ok := excelMap[match]; ok {
-			element := Element{
-				Name: strings.ReplaceAll(compName, ".", ""),
-				Property: []Property{{
-					Name:  "coverage.m2",
-					Value: fmt.Sprintf("%v", val),
-				}},
-			}
-			result.Items = append(result.Items, element)
+			element := result.AddElement(strings.ReplaceAll(compName, ".", ""))
+			element.SetProperty("coverage.m2", fmt.Sprintf("%v", val))
 		}
 	}
 
-	outputFile := filepath.Join(Public_data.M2OutputlPath, "M2.ldi.xml")
-	out, err := xml.MarshalIndent(result, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("XML 직렬화 실패: %v", err)
-	}
-
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(outputFile, append(header, out...), 0644); err != nil {
-		return fmt.Errorf("ldi.xml 쓰기 실패: %v", err)
+	outputFile := filepath.Join(cfg.MetricOutputDir("M2"), "M2.ldi.xml")
+	if err := result.Save(outputFile); err != nil {
+		return nil, fmt.Errorf("ldi.xml 쓰기 실패: %v", err)
 	}
 	fmt.Printf("📄 M2 지표 계산 완료: %s\n", outputFile)
-	return nil
+	return result, nil
 }
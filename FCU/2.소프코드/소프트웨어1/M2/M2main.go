@@ -5,17 +5,32 @@ import (
 	//"path/filepath"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M2/File_Utils_M2"
 	"FCU_Tools/M2/LDI_M2_Create"
+	"FCU_Tools/Metric_Registry"
 	"FCU_Tools/Public_data"
 )
 
-func M2_main() {
-	// Public_data.ConnectorFilePath(예: D:\test\testset\asw.csv)에서 입력 디렉터리를 유도합니다.
-	base := strings.TrimSpace(Public_data.ConnectorFilePath)
+// Metric은 M2 지표를 Metric_Registry에 등록하기 위한 구현입니다.
+type Metric struct{}
+
+func init() {
+	Metric_Registry.Register(Metric{})
+}
+
+func (Metric) Name() string       { return "M2" }
+func (Metric) Requires() []string { return []string{"SWC"} }
+
+// After: M1이 추가한 계층 요소에도 속성을 붙이도록 M1이 활성화되어 있으면 그 다음에 실행합니다.
+func (Metric) After() []string { return []string{"M1"} }
+
+// Compute는 M2 출력 디렉터리를 준비하고 M2 조각 문서를 생성합니다.
+func (Metric) Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	// cfg.ConnectorFilePath(예: D:\test\testset\asw.csv)에서 입력 디렉터리를 유도합니다.
+	base := strings.TrimSpace(cfg.ConnectorFilePath)
 	if base == "" {
-		fmt.Println("M2 자동 경로 설정 실패: ConnectorFilePath가 비어 있습니다.")
-		return
+		return nil, fmt.Errorf("M2 자동 경로 설정 실패: ConnectorFilePath가 비어 있습니다.")
 	}
 	// dir := filepath.Dir(base)
 
@@ -25,11 +40,14 @@ func M2_main() {
 	// 	return
 	// }
 
-	if err := File_Utils_M2.PrepareM2OutputDir(); err != nil {
-		fmt.Println("M2 출력 디렉토리 준비 실패：", err)
-		return
+	if err := File_Utils_M2.PrepareM2OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M2 출력 디렉토리 준비 실패：%v", err)
 	}
 
-	File_Utils_M2.GenerateM2LDIXml()
-	LDI_M2_Create.MergeM2ToMainLDI()
+	return File_Utils_M2.GenerateM2LDIXml(cfg)
+}
+
+// Merge는 M2 조각 문서를 주 LDI에 병합합니다.
+func (Metric) Merge(mainLDI, frag *LDI_Model.Document) error {
+	return LDI_M2_Create.MergeM2ToMainLDI(mainLDI, frag)
 }
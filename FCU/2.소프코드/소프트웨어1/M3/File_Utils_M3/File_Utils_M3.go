@@ -1,12 +1,11 @@
 package File_Utils_M3
 
 import (
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/Public_data"
 	"FCU_Tools/SWC_Dependence"
 	"encoding/csv"
-	"encoding/xml"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -33,16 +32,10 @@ import (
 // PrepareM2OutputDir는 M3의 출력 디렉터리를 준비한다.
 //
 // 프로세스:
-//  1. 현재 작업 디렉터리를 가져온다.
-//  2. 경로를 <작업 디렉터리>/M3/output으로 결합한다.
-//  3. output이 이미 존재하면 삭제 후 새로 생성한다.
-//  4. 경로를 Public_data.M3OutputlPath에 저장한다.
-func PrepareM3OutputDir() error {
-	basePath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("작업 디렉토리를 가져오지 못했습니다.: %v", err)
-	}
-	outputPath := filepath.Join(basePath, "M3", "output")
+//  1. cfg.MetricOutputDir("M3")로 <WorkDir>/M3/output 경로를 얻는다.
+//  2. output이 이미 존재하면 삭제 후 새로 생성한다.
+func PrepareM3OutputDir(cfg *Public_data.RunConfig) error {
+	outputPath := cfg.MetricOutputDir("M3")
 
 	if _, err := os.Stat(outputPath); err == nil {
 		if err := os.RemoveAll(outputPath); err != nil {
@@ -54,9 +47,6 @@ func PrepareM3OutputDir() error {
 		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다.: %v", err)
 	}
 
-	// Public_data에 경로 저장 변수
-	Public_data.M3OutputlPath = outputPath
-
 	return nil
 }
 
@@ -74,31 +64,16 @@ func PrepareM3OutputDir() error {
 //     - coverage.m3 = 위반 횟수
 //     - coverage.m3demo = 전체 의존 횟수
 //  5. LDI 파일을 M3/output/M3.ldi.xml에 출력하고 완료 메시지 출력.
-func GenerateM3LDIXml() error {
-	type Property struct {
-		XMLName xml.Name `xml:"property"`
-		Name    string   `xml:"name,attr"`
-		Value   string   `xml:",chardata"`
-	}
-	type Element struct {
-		XMLName  xml.Name   `xml:"element"`
-		Name     string     `xml:"name,attr"`
-		Property []Property `xml:"property"`
-	}
-	type Root struct {
-		XMLName xml.Name  `xml:"ldi"`
-		Items   []Element `xml:"element"`
-	}
-
-	dependencies, err := SWC_Dependence.ExtractDependenciesRawFromASW(Public_data.ConnectorFilePath)
+func GenerateM3LDIXml(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	dependencies, err := SWC_Dependence.ExtractDependenciesRawFromASW(cfg.ConnectorFilePath, cfg.ASWColumns, cfg.NMPolicy)
 	if err != nil {
-		return fmt.Errorf("ASW 종속성 읽기 실패: %v", err)
+		return nil, fmt.Errorf("ASW 종속성 읽기 실패: %v", err)
 	}
 
 	// component_info.csv 읽기
-	f, err := os.Open(Public_data.M3component_infoxlsxPath)
+	f, err := os.Open(cfg.M3component_infoxlsxPath)
 	if err != nil {
-		return fmt.Errorf("component_info.csv 열기 실패: %v", err)
+		return nil, fmt.Errorf("component_info.csv 열기 실패: %v", err)
 	}
 	defer f.Close()
 
@@ -108,7 +83,10 @@ func GenerateM3LDIXml() error {
 
 	rows, err := r.ReadAll()
 	if err != nil {
-		return fmt.Errorf("component_info.csv 읽기 실패: %v", err)
+		return nil, fmt.Errorf("component_info.csv 읽기 실패: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("component_info.csv가 비어 있습니다(헤더 행도 없음)")
 	}
 
 	layerMap := make(map[string]int)
@@ -123,9 +101,9 @@ func GenerateM3LDIXml() error {
 		}
 	}
 
-	m3TxtPath := filepath.Join(Public_data.M3OutputlPath, "M3.txt")
+	m3TxtPath := filepath.Join(cfg.MetricOutputDir("M3"), "M3.txt")
 	if err := os.Remove(m3TxtPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("기존 M3.txt 삭제 실패: %v", err)
+		return nil, fmt.Errorf("기존 M3.txt 삭제 실패: %v", err)
 	}
 
 	violationMap := make(map[string]int)
@@ -158,11 +136,11 @@ func GenerateM3LDIXml() error {
 				line := fmt.Sprintf("%s-->%s\n", from, to)
 				f, err := os.OpenFile(m3TxtPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 				if err != nil {
-					return fmt.Errorf("M3.txt 파일 열기 실패: %v", err)
+					return nil, fmt.Errorf("M3.txt 파일 열기 실패: %v", err)
 				}
 				if _, err := f.WriteString(line); err != nil {
 					f.Close()
-					return fmt.Errorf("M3.txt 기록 실패: %v", err)
+					return nil, fmt.Errorf("M3.txt 기록 실패: %v", err)
 				}
 				f.Close()
 			} else {
@@ -171,29 +149,19 @@ func GenerateM3LDIXml() error {
 		}
 	}
 
-	var result Root
+	result := LDI_Model.New()
 	for comp, demoCount := range sourceCount {
 		violationCount := violationMap[comp]
-		elem := Element{
-			Name: comp,
-			Property: []Property{
-				{Name: "coverage.m3", Value: fmt.Sprintf("%d", violationCount)},
-				{Name: "coverage.m3demo", Value: fmt.Sprintf("%d", demoCount)},
-			},
-		}
-		result.Items = append(result.Items, elem)
+		elem := result.AddElement(comp)
+		elem.AddProperty("coverage.m3", fmt.Sprintf("%d", violationCount))
+		elem.AddProperty("coverage.m3demo", fmt.Sprintf("%d", demoCount))
 	}
 
-	outPath := filepath.Join(Public_data.M3OutputlPath, "M3.ldi.xml")
-	output, err := xml.MarshalIndent(result, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("XML 생성 실패: %v", err)
-	}
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(outPath, append(header, output...), 0644); err != nil {
-		return fmt.Errorf("M3.ldi.xml 저장 실패: %v", err)
+	outPath := filepath.Join(cfg.MetricOutputDir("M3"), "M3.ldi.xml")
+	if err := result.Save(outPath); err != nil {
+		return nil, fmt.Errorf("M3.ldi.xml 저장 실패: %v", err)
 	}
 
 	fmt.Println("📄 M3 및 m3demo 지표 계산 완료:", outPath)
-	return nil
+	return result, nil
 }
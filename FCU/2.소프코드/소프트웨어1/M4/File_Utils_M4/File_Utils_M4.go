@@ -2,13 +2,12 @@ package File_Utils_M4
 
 import (
 	"encoding/csv"
-	"encoding/xml"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/Public_data"
 	"FCU_Tools/SWC_Dependence"
 )
@@ -16,16 +15,10 @@ import (
 // PrepareM4OutputDir M4의 출력 디렉터리를 초기화하고 준비한다.
 //
 // 프로세스:
-//   1) 현재 작업 디렉터리 basePath를 가져온다.
-//   2) <basePath>/M4/output 경로를 생성한다.
-//   3) output 디렉터리가 이미 존재하면 삭제 후 새로 만든다.
-//   4) 경로를 Public_data.M4OutputlPath에 저장하여 이후 모듈에서 사용한다.
-func PrepareM4OutputDir() error {
-	basePath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("작업 디렉토리를 가져오지 못했습니다: %v", err)
-	}
-	outputPath := filepath.Join(basePath, "M4", "output")
+//   1) cfg.MetricOutputDir("M4")로 <WorkDir>/M4/output 경로를 얻는다.
+//   2) output 디렉터리가 이미 존재하면 삭제 후 새로 만든다.
+func PrepareM4OutputDir(cfg *Public_data.RunConfig) error {
+	outputPath := cfg.MetricOutputDir("M4")
 
 	if _, err := os.Stat(outputPath); err == nil {
 		if err := os.RemoveAll(outputPath); err != nil {
@@ -37,9 +30,6 @@ func PrepareM4OutputDir() error {
 		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다: %v", err)
 	}
 
-	// Public_data에 경로 저장 변수
-	Public_data.M4OutputlPath = outputPath
-
 	return nil
 }
 
@@ -61,26 +51,11 @@ func PrepareM4OutputDir() error {
 //        - coverage.m4     = 위반 연결 수
 //        - coverage.m4demo = 전체 의존 수
 //   5) XML로 직렬화하여 M4/output/M4.ldi.xml에 출력한다.
-func GenerateM4LDIXml() error {
-	type Property struct {
-		XMLName xml.Name `xml:"property"`
-		Name    string   `xml:"name,attr"`
-		Value   string   `xml:",chardata"`
-	}
-	type Element struct {
-		XMLName  xml.Name   `xml:"element"`
-		Name     string     `xml:"name,attr"`
-		Property []Property `xml:"property"`
-	}
-	type Root struct {
-		XMLName xml.Name  `xml:"ldi"`
-		Items   []Element `xml:"element"`
-	}
-
+func GenerateM4LDIXml(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
 	// 연결 정보를 로드합니다 (원본 연결 유지)
-	connectorDeps, err := SWC_Dependence.ExtractDependenciesRawFromASW(Public_data.ConnectorFilePath)
+	connectorDeps, err := SWC_Dependence.ExtractDependenciesRawFromASW(cfg.ConnectorFilePath, cfg.ASWColumns, cfg.NMPolicy)
 	if err != nil {
-		return fmt.Errorf("asw 종속성 읽기 실패: %v", err)
+		return nil, fmt.Errorf("asw 종속성 읽기 실패: %v", err)
 	}
 	totalLinks := 0
 	for _, deps := range connectorDeps {
@@ -89,10 +64,10 @@ func GenerateM4LDIXml() error {
 	//fmt.Printf("🔗 총 연결 개수 로드됨: %d\n", totalLinks)
 
 	// 컴포넌트 정보를 로드합니다 (component_info.csv)
-	// 주의: Public_data.M3component_infoxlsxPath 변수명은 그대로지만, 실제로는 CSV 경로를 담고 있다.
-	compFile, err := os.Open(Public_data.M3component_infoxlsxPath)
+	// 주의: cfg.M3component_infoxlsxPath 변수명은 그대로지만, 실제로는 CSV 경로를 담고 있다.
+	compFile, err := os.Open(cfg.M3component_infoxlsxPath)
 	if err != nil {
-		return fmt.Errorf("component_info.csv 열기 실패: %v", err)
+		return nil, fmt.Errorf("component_info.csv 열기 실패: %v", err)
 	}
 	defer compFile.Close()
 
@@ -102,7 +77,10 @@ func GenerateM4LDIXml() error {
 
 	compRows, err := csvReader.ReadAll()
 	if err != nil {
-		return fmt.Errorf("component_info.csv 컨텐츠를 읽지 못했습니다: %v", err)
+		return nil, fmt.Errorf("component_info.csv 컨텐츠를 읽지 못했습니다: %v", err)
+	}
+	if len(compRows) == 0 {
+		return nil, fmt.Errorf("component_info.csv가 비어 있습니다(헤더 행도 없음)")
 	}
 
 	type CompMeta struct {
@@ -121,9 +99,9 @@ func GenerateM4LDIXml() error {
 		}
 	}
 
-	m4TxtPath := filepath.Join(Public_data.M4OutputlPath, "M4.txt")
+	m4TxtPath := filepath.Join(cfg.MetricOutputDir("M4"), "M4.txt")
 	if err := os.Remove(m4TxtPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("기존 M4.txt 삭제 실패: %v", err)
+		return nil, fmt.Errorf("기존 M4.txt 삭제 실패: %v", err)
 	}
 
 	violationMap := make(map[string]int)
@@ -168,11 +146,11 @@ func GenerateM4LDIXml() error {
 				line := fmt.Sprintf("%s-->%s\n", from, to)
 				f, err := os.OpenFile(m4TxtPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 				if err != nil {
-					return fmt.Errorf("M4.txt 파일을 열 수 없습니다: %v", err)
+					return nil, fmt.Errorf("M4.txt 파일을 열 수 없습니다: %v", err)
 				}
 				if _, err := f.WriteString(line); err != nil {
 					f.Close()
-					return fmt.Errorf("M4.txt에 기록할 수 없습니다: %v", err)
+					return nil, fmt.Errorf("M4.txt에 기록할 수 없습니다: %v", err)
 				}
 				f.Close()
 			} else {
@@ -181,28 +159,18 @@ func GenerateM4LDIXml() error {
 		}
 	}
 
-	var result Root
+	result := LDI_Model.New()
 	for comp, demoCount := range sourceCount {
 		violationCount := violationMap[comp]
-		elem := Element{
-			Name: comp,
-			Property: []Property{
-				{Name: "coverage.m4", Value: fmt.Sprintf("%d", violationCount)},
-				{Name: "coverage.m4demo", Value: fmt.Sprintf("%d", demoCount)},
-			},
-		}
-		result.Items = append(result.Items, elem)
+		elem := result.AddElement(comp)
+		elem.AddProperty("coverage.m4", fmt.Sprintf("%d", violationCount))
+		elem.AddProperty("coverage.m4demo", fmt.Sprintf("%d", demoCount))
 	}
 
-	outPath := filepath.Join(Public_data.M4OutputlPath, "M4.ldi.xml")
-	output, err := xml.MarshalIndent(result, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("XML 컨텐트 생성 실패: %v", err)
-	}
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(outPath, append(header, output...), 0644); err != nil {
-		return fmt.Errorf("M4.ldi.xml 파일을 쓰는 데 실패했습니다: %v", err)
+	outPath := filepath.Join(cfg.MetricOutputDir("M4"), "M4.ldi.xml")
+	if err := result.Save(outPath); err != nil {
+		return nil, fmt.Errorf("M4.ldi.xml 파일을 쓰는 데 실패했습니다: %v", err)
 	}
 	fmt.Println("📄 M4 및 m4demo 지표 계산 완료:", outPath)
-	return nil
+	return result, nil
 }
@@ -1,24 +1,39 @@
 package M4main
 
 import (
-	"fmt"
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M4/File_Utils_M4"
 	"FCU_Tools/M4/LDI_M4_Create"
+	"FCU_Tools/Metric_Registry"
+	"FCU_Tools/Public_data"
+	"fmt"
 )
-// M4_main 은 M4 지표 계산과 병합의 총 진입점이다.  
-func M4_main() {
 
-	//   1) File_Utils_M4.PrepareM4OutputDir를 호출하여 출력 디렉터리를 초기화한다.  
- 	if err := File_Utils_M4.PrepareM4OutputDir(); err != nil {
-		fmt.Println("M4 출력 디렉토리 준비 실패：", err)
-		return
-	}
+// Metric은 M4 지표 계산과 병합을 Metric_Registry에 등록하기 위한 구현이다.
+type Metric struct{}
 
-	//   2) File_Utils_M4.GenerateM4LDIXml을 호출하여 지표를 계산하고 M4.ldi.xml과 M4.txt를 생성한다.  
-	File_Utils_M4.GenerateM4LDIXml()
+func init() {
+	Metric_Registry.Register(Metric{})
+}
 
-	//   3) LDI_M4_Create.MergeM4ToMainLDI를 호출하여 결과를 주 LDI 파일 result.ldi.xml에 병합한다.  
-	LDI_M4_Create.MergeM4ToMainLDI()
+func (Metric) Name() string       { return "M4" }
+func (Metric) Requires() []string { return []string{"SWC"} }
+
+// After: M1이 추가한 계층 요소에도 속성을 붙이도록 M1이 활성화되어 있으면 그 다음에 실행합니다.
+func (Metric) After() []string { return []string{"M1"} }
+
+// Compute는 M4 지표를 계산하여 조각 문서를 반환한다.
+func (Metric) Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	//   1) File_Utils_M4.PrepareM4OutputDir를 호출하여 출력 디렉터리를 초기화한다.
+	if err := File_Utils_M4.PrepareM4OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M4 출력 디렉토리 준비 실패：%v", err)
+	}
 
+	//   2) File_Utils_M4.GenerateM4LDIXml을 호출하여 지표를 계산하고 M4.ldi.xml과 M4.txt를 생성한다.
+	return File_Utils_M4.GenerateM4LDIXml(cfg)
+}
 
-}
\ No newline at end of file
+// Merge는 LDI_M4_Create.MergeM4ToMainLDI를 호출하여 결과를 주 LDI에 병합한다.
+func (Metric) Merge(mainLDI, frag *LDI_Model.Document) error {
+	return LDI_M4_Create.MergeM4ToMainLDI(mainLDI, frag)
+}
@@ -2,29 +2,22 @@ package File_Utils_M5
 
 import (
 	"encoding/csv"
-	"encoding/xml"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/Public_data"
 )
 
 // PrepareM5OutputDir M5의 출력 디렉터리를 초기화하고 준비한다.
 //
 // 프로세스:
-//   1) 현재 작업 디렉터리 basePath를 가져온다.
-//   2) <basePath>/M5/output 경로를 생성한다.
-//   3) output 디렉터리가 이미 존재하면 삭제 후 새로 만든다.
-//   4) 경로를 Public_data.M5OutputlPath에 저장한다.
-func PrepareM5OutputDir() error {
-	basePath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("작업 디렉토리를 가져오지 못했습니다: %v", err)
-	}
-	outputPath := filepath.Join(basePath, "M5", "output")
+//   1) cfg.MetricOutputDir("M5")로 <WorkDir>/M5/output 경로를 얻는다.
+//   2) output 디렉터리가 이미 존재하면 삭제 후 새로 만든다.
+func PrepareM5OutputDir(cfg *Public_data.RunConfig) error {
+	outputPath := cfg.MetricOutputDir("M5")
 
 	if _, err := os.Stat(outputPath); err == nil {
 		if err := os.RemoveAll(outputPath); err != nil {
@@ -36,9 +29,6 @@ func PrepareM5OutputDir() error {
 		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다: %v", err)
 	}
 
-	// Public_data에 경로 저장 변수
-	Public_data.M5OutputlPath = outputPath
-
 	return nil
 }
 
@@ -54,28 +44,13 @@ func PrepareM5OutputDir() error {
 //   4) 각 컴포넌트에 대해 coverage.m5demo = 1을 고정 추가한다 (데모용 기준값).
 //   5) 모든 컴포넌트를 <element name="..."><property .../></element> 형태로 변환하여
 //      M5/output/M5.ldi.xml에 기록한다.
-func GenerateM5LDIXml() error {
-	type Property struct {
-		XMLName xml.Name `xml:"property"`
-		Name    string   `xml:"name,attr"`
-		Value   string   `xml:",chardata"`
-	}
-	type Element struct {
-		XMLName  xml.Name   `xml:"element"`
-		Name     string     `xml:"name,attr"`
-		Property []Property `xml:"property"`
-	}
-	type Root struct {
-		XMLName xml.Name  `xml:"ldi"`
-		Items   []Element `xml:"element"`
-	}
-
+func GenerateM5LDIXml(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
 	// component_info.csv 열기
-	// 주의: Public_data.M3component_infoxlsxPath 변수명은 그대로지만,
+	// 주의: cfg.M3component_infoxlsxPath 변수명은 그대로지만,
 	// 실제로는 component_info.csv 경로를 담고 있다(M3/M4와 동일 패턴).
-	compInfoFile, err := os.Open(Public_data.M3component_infoxlsxPath)
+	compInfoFile, err := os.Open(cfg.M3component_infoxlsxPath)
 	if err != nil {
-		return fmt.Errorf("component_info.csv 열기 실패: %v", err)
+		return nil, fmt.Errorf("component_info.csv 열기 실패: %v", err)
 	}
 	defer compInfoFile.Close()
 
@@ -85,10 +60,13 @@ func GenerateM5LDIXml() error {
 
 	rows, err := reader.ReadAll()
 	if err != nil {
-		return fmt.Errorf("component_info.csv 컨텐츠를 읽지 못했습니다.: %v", err)
+		return nil, fmt.Errorf("component_info.csv 컨텐츠를 읽지 못했습니다.: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("component_info.csv가 비어 있습니다(헤더 행도 없음)")
 	}
 
-	var result Root
+	result := LDI_Model.New()
 	// 첫 행은 헤더라고 가정하고 rows[1:]부터 처리 (기존 xlsx 로직과 동일)
 	for _, row := range rows[1:] {
 		if len(row) >= 4 {
@@ -100,34 +78,18 @@ func GenerateM5LDIXml() error {
 				m5 = "1"
 			}
 
-			elem := Element{
-				Name: name,
-				Property: []Property{
-					{
-						Name:  "coverage.m5",
-						Value: m5,
-					},
-					{
-						Name:  "coverage.m5demo",
-						Value: "1",
-					},
-				},
-			}
-			result.Items = append(result.Items, elem)
+			elem := result.AddElement(name)
+			elem.AddProperty("coverage.m5", m5)
+			elem.AddProperty("coverage.m5demo", "1")
 		}
 	}
 
 	// XML 파일 쓰기
-	outPath := filepath.Join(Public_data.M5OutputlPath, "M5.ldi.xml")
-	output, err := xml.MarshalIndent(result, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("XML 컨텐트 생성 실패: %v", err)
-	}
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(outPath, append(header, output...), 0644); err != nil {
-		return fmt.Errorf("M5 ldi.xml 파일 쓰기 실패: %v", err)
+	outPath := filepath.Join(cfg.MetricOutputDir("M5"), "M5.ldi.xml")
+	if err := result.Save(outPath); err != nil {
+		return nil, fmt.Errorf("M5 ldi.xml 파일 쓰기 실패: %v", err)
 	}
 
 	fmt.Println("📄 M5 및 m5demo 지표 계산 완료:", outPath)
-	return nil
+	return result, nil
 }
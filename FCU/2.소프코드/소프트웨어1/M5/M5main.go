@@ -1,25 +1,39 @@
 package M5main
 
 import (
-	"fmt"
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M5/File_Utils_M5"
 	"FCU_Tools/M5/LDI_M5_Create"
+	"FCU_Tools/Metric_Registry"
+	"FCU_Tools/Public_data"
+	"fmt"
 )
 
-// M5_main 은 M5 지표의 총 진입점이다: 출력 디렉터리를 준비하고,  
-// M5 LDI 파일을 생성한 후 주 LDI에 병합한다.  
+// Metric은 M5 지표를 Metric_Registry에 등록하기 위한 구현이다: 출력 디렉터리를 준비하고,
+// M5 LDI 조각을 생성한 후 주 LDI에 병합한다.
+type Metric struct{}
+
+func init() {
+	Metric_Registry.Register(Metric{})
+}
+
+func (Metric) Name() string       { return "M5" }
+func (Metric) Requires() []string { return []string{"SWC"} }
 
-func M5_main() {
+// After: M1이 추가한 계층 요소에도 속성을 붙이도록 M1이 활성화되어 있으면 그 다음에 실행합니다.
+func (Metric) After() []string { return []string{"M1"} }
 
-	//   1) File_Utils_M5.PrepareM5OutputDir를 호출하여 출력 디렉토리를 초기화한다.  
-	if err := File_Utils_M5.PrepareM5OutputDir(); err != nil {
-		fmt.Println("5 출력 디렉토리 준비 실패: ", err)
-		return
+func (Metric) Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	//   1) File_Utils_M5.PrepareM5OutputDir를 호출하여 출력 디렉토리를 초기화한다.
+	if err := File_Utils_M5.PrepareM5OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M5 출력 디렉토리 준비 실패: %v", err)
 	}
 
-	//   2) File_Utils_M5.GenerateM5LDIXml을 호출하여 component_info.csv을 읽고 M5.ldi.xml을 생성한다.  
-	File_Utils_M5.GenerateM5LDIXml()
+	//   2) File_Utils_M5.GenerateM5LDIXml을 호출하여 component_info.csv을 읽고 M5.ldi.xml을 생성한다.
+	return File_Utils_M5.GenerateM5LDIXml(cfg)
+}
 
-	//   3) LDI_M5_Create.MergeM5ToMainLDI를 호출하여 m5 및 m5demo 지표를 주 LDI 파일에 병합한다.  
-	LDI_M5_Create.MergeM5ToMainLDI()
+// Merge는 LDI_M5_Create.MergeM5ToMainLDI를 호출하여 m5 및 m5demo 지표를 주 LDI에 병합한다.
+func (Metric) Merge(mainLDI, frag *LDI_Model.Document) error {
+	return LDI_M5_Create.MergeM5ToMainLDI(mainLDI, frag)
 }
@@ -1,14 +1,12 @@
 package File_Utils_M6
 
 import (
-	"encoding/csv"
-	"encoding/xml"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/Public_data"
 	"FCU_Tools/SWC_Dependence"
 )
@@ -16,16 +14,10 @@ import (
 // PrepareM6OutputDir M6의 출력 디렉터리를 초기화하고 준비한다.
 //
 // 프로세스:
-//  1. 현재 작업 디렉터리 basePath를 가져온다.
-//  2. <basePath>/M6/output 경로를 생성한다.
-//  3. output 디렉터리가 이미 존재하면 삭제 후 새로 만든다.
-//  4. 경로를 Public_data.M6OutputlPath에 저장한다.
-func PrepareM6OutputDir() error {
-	basePath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("작업 디렉토리를 가져오지 못했습니다: %v", err)
-	}
-	outputPath := filepath.Join(basePath, "M6", "output")
+//  1. cfg.MetricOutputDir("M6")로 <WorkDir>/M6/output 경로를 얻는다.
+//  2. output 디렉터리가 이미 존재하면 삭제 후 새로 만든다.
+func PrepareM6OutputDir(cfg *Public_data.RunConfig) error {
+	outputPath := cfg.MetricOutputDir("M6")
 
 	if _, err := os.Stat(outputPath); err == nil {
 		if err := os.RemoveAll(outputPath); err != nil {
@@ -37,9 +29,6 @@ func PrepareM6OutputDir() error {
 		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다.: %v", err)
 	}
 
-	// Public_data에 경로 저장 변수
-	Public_data.M6OutputlPath = outputPath
-
 	return nil
 }
 
@@ -47,7 +36,7 @@ func PrepareM6OutputDir() error {
 // M6 지표를 계산하고 M6.ldi.xml 및 M6.txt를 생성한다.
 //
 // 계산 로직:
-//  1. asw.csv을 열고 5번째 열(ASIL 등급 A/B/C/D)을 읽어
+//  1. asw.csv을 열고 ASIL 헤더 열(A/B/C/D)을 읽어
 //     숫자 등급 1~4로 매핑하여 asilLevelMap에 저장한다.
 //  2. SWC_Dependence.ExtractDependenciesRawFromASW 호출 → 컴포넌트 의존성(from→to, 연결 횟수와 인터페이스 타입 포함) 읽기.
 //  3. 의존성 순회:
@@ -59,54 +48,25 @@ func PrepareM6OutputDir() error {
 //     - coverage.m6     = 위반 의존 횟수
 //     - coverage.m6demo = 전체 의존 횟수
 //  5. 결과를 M6/output/M6.ldi.xml에 출력한다.
-func GenerateM6LDIXml() error {
-	type Property struct {
-		XMLName xml.Name `xml:"property"`
-		Name    string   `xml:"name,attr"`
-		Value   string   `xml:",chardata"`
-	}
-	type Element struct {
-		XMLName  xml.Name   `xml:"element"`
-		Name     string     `xml:"name,attr"`
-		Property []Property `xml:"property"`
-	}
-	type Root struct {
-		XMLName xml.Name  `xml:"ldi"`
-		Items   []Element `xml:"element"`
-	}
-
-	//  Step 1: asw.csv에서 ASIL 등급(5열) 추출
-	asilFile, err := os.Open(Public_data.ConnectorFilePath)
+func GenerateM6LDIXml(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	//  Step 1: asw.csv에서 ASIL 등급 추출(열 위치는 헤더 이름으로 찾습니다)
+	rows, err := SWC_Dependence.LoadASWRows(cfg.ConnectorFilePath, cfg.ASWColumns)
 	if err != nil {
-		return fmt.Errorf("asw.csv 열기 실패: %v", err)
-	}
-	defer asilFile.Close()
-
-	reader := csv.NewReader(asilFile)
-	// 각 행의 컬럼 수가 달라도 읽을 수 있도록 설정
-	reader.FieldsPerRecord = -1
-
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("asw.csv 컨텐츠를 읽지 못했습니다: %v", err)
+		return nil, fmt.Errorf("asw.csv 컨텐츠를 읽지 못했습니다: %v", err)
 	}
 
 	asilMap := map[string]int{"QM": 0, "A": 1, "B": 2, "C": 3, "D": 4}
 	asilLevelMap := make(map[string]int)
 
-	// 첫 행은 헤더라고 가정하고 rows[1:]부터 처리
-	for _, row := range rows[1:] {
-		if len(row) < 5 {
-			continue
-		}
-		component := strings.TrimSpace(row[3])
+	for _, row := range rows {
+		component := row.Component
 		if component == "" {
 			continue
 		}
 		if _, exists := asilLevelMap[component]; exists {
 			continue
 		}
-		asil := strings.ToUpper(strings.TrimSpace(row[4]))
+		asil := strings.ToUpper(row.ASIL)
 		if strings.HasPrefix(asil, "ASIL-") {
 			asil = strings.TrimPrefix(asil, "ASIL-")
 		}
@@ -116,16 +76,16 @@ func GenerateM6LDIXml() error {
 	}
 
 	//  Step 2: 의존성 읽기(각 연결마다)
-	connectorDeps, err := SWC_Dependence.ExtractDependenciesRawFromASW(Public_data.ConnectorFilePath)
+	connectorDeps, err := SWC_Dependence.ExtractDependenciesRawFromASW(cfg.ConnectorFilePath, cfg.ASWColumns, cfg.NMPolicy)
 	if err != nil {
-		return fmt.Errorf("asw 연결 분석 실패: %v", err)
+		return nil, fmt.Errorf("asw 연결 분석 실패: %v", err)
 	}
 
 	violationMap := make(map[string]int)
 	sourceCount := make(map[string]int)
 
 	//  M6.txt 파일은 위반 연결을 기록합니다.
-	m6TxtPath := filepath.Join(Public_data.M6OutputlPath, "M6.txt")
+	m6TxtPath := filepath.Join(cfg.MetricOutputDir("M6"), "M6.txt")
 	_ = os.Remove(m6TxtPath)
 
 	for from, targets := range connectorDeps {
@@ -160,29 +120,19 @@ func GenerateM6LDIXml() error {
 	}
 
 	//  Step 3: XML 출력 생성
-	var result Root
+	result := LDI_Model.New()
 	for name, count := range sourceCount {
 		violation := violationMap[name]
-		elem := Element{
-			Name: name,
-			Property: []Property{
-				{Name: "coverage.m6", Value: fmt.Sprintf("%d", violation)},
-				{Name: "coverage.m6demo", Value: fmt.Sprintf("%d", count)},
-			},
-		}
-		result.Items = append(result.Items, elem)
+		elem := result.AddElement(name)
+		elem.AddProperty("coverage.m6", fmt.Sprintf("%d", violation))
+		elem.AddProperty("coverage.m6demo", fmt.Sprintf("%d", count))
 	}
 
-	outPath := filepath.Join(Public_data.M6OutputlPath, "M6.ldi.xml")
-	output, err := xml.MarshalIndent(result, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("XML 직렬화 실패: %v", err)
-	}
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(outPath, append(header, output...), 0644); err != nil {
-		return fmt.Errorf("M6.ldi.xml 쓰기 실패: %v", err)
+	outPath := filepath.Join(cfg.MetricOutputDir("M6"), "M6.ldi.xml")
+	if err := result.Save(outPath); err != nil {
+		return nil, fmt.Errorf("M6.ldi.xml 쓰기 실패: %v", err)
 	}
 
 	fmt.Println("📄 M6 및 m6demo 지표 계산 완료:", outPath)
-	return nil
+	return result, nil
 }
@@ -1,25 +1,39 @@
 package M6main
 
 import (
-	"fmt"
+	"FCU_Tools/LDI_Model"
 	"FCU_Tools/M6/File_Utils_M6"
 	"FCU_Tools/M6/LDI_M6_Create"
+	"FCU_Tools/Metric_Registry"
+	"FCU_Tools/Public_data"
+	"fmt"
 )
-// M6_main 은 M6 프로세스의 총 진입점이다: 출력 디렉터리를 준비하고,  
-// M6 LDI 파일을 생성한 후 주 LDI에 병합한다.  
-
-func M6_main() {
-	//   1) File_Utils_M6.PrepareM6OutputDir를 호출하여 출력 디렉터리를 초기화한다.  
-	if err := File_Utils_M6.PrepareM6OutputDir(); err != nil {
-		fmt.Println("6 출력 디렉토리 준비 실패: ", err)
-		return
-	}
 
-	//   2) File_Utils_M6.GenerateM6LDIXml을 호출하여 M6 지표를 계산하고 M6.ldi.xml 및 M6.txt를 생성한다.  
-	File_Utils_M6.GenerateM6LDIXml()
-	
-	//   3) LDI_M6_Create.MergeM6ToMainLDI를 호출하여 M6 지표를 주 LDI 파일 result.ldi.xml에 병합한다.  
-	LDI_M6_Create.MergeM6ToMainLDI()
+// Metric은 M6 지표를 Metric_Registry에 등록하기 위한 구현이다: 출력 디렉터리를 준비하고,
+// M6 LDI 조각을 생성한 후 주 LDI에 병합한다.
+type Metric struct{}
+
+func init() {
+	Metric_Registry.Register(Metric{})
+}
+
+func (Metric) Name() string       { return "M6" }
+func (Metric) Requires() []string { return []string{"SWC"} }
+
+// After: M1이 추가한 계층 요소에도 속성을 붙이도록 M1이 활성화되어 있으면 그 다음에 실행합니다.
+func (Metric) After() []string { return []string{"M1"} }
+
+func (Metric) Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	//   1) File_Utils_M6.PrepareM6OutputDir를 호출하여 출력 디렉터리를 초기화한다.
+	if err := File_Utils_M6.PrepareM6OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M6 출력 디렉토리 준비 실패: %v", err)
+	}
 
+	//   2) File_Utils_M6.GenerateM6LDIXml을 호출하여 M6 지표를 계산하고 M6.ldi.xml 및 M6.txt를 생성한다.
+	return File_Utils_M6.GenerateM6LDIXml(cfg)
+}
 
-}
\ No newline at end of file
+// Merge는 LDI_M6_Create.MergeM6ToMainLDI를 호출하여 M6 지표를 주 LDI에 병합한다.
+func (Metric) Merge(mainLDI, frag *LDI_Model.Document) error {
+	return LDI_M6_Create.MergeM6ToMainLDI(mainLDI, frag)
+}
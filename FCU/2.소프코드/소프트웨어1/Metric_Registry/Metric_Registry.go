@@ -0,0 +1,361 @@
+// Metric_Registry.go
+package Metric_Registry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"FCU_Tools/LDI_Model"
+	"FCU_Tools/Public_data"
+)
+
+// Metric은 하나의 지표(또는 분석 단계)를 나타냅니다.
+//   - Name: 지표 이름(예: "SWC", "M1" ... "M6"). 레지스트리 안에서 유일해야 합니다.
+//   - Requires: 먼저 실행되어야 하는 지표 이름 목록입니다.
+//   - Compute: cfg의 경로를 사용해 지표를 계산하고, 주 LDI에 병합할 조각(fragment) 문서를 반환합니다.
+//     오류와 함께 nil이 아닌 조각을 반환하면 일부만 성공한 것(부분 실패)으로 보고, 조각은 병합합니다.
+type Metric interface {
+	Name() string
+	Requires() []string
+	Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error)
+}
+
+// Merger는 기본 병합 규칙(주 LDI에 있는 요소에만 없는 속성을 추가) 대신
+// 별도의 병합 방식이 필요한 지표가 구현합니다.
+type Merger interface {
+	Merge(main, frag *LDI_Model.Document) error
+}
+
+// Follower는 선행 조건(Requires)은 아니지만, 활성화되어 있다면 먼저 실행되어야 하는 지표가 있을 때 구현합니다.
+// 예: M2~M6은 M1이 추가한 계층 요소에도 속성을 붙이므로 M1 다음에 실행되어야 합니다.
+type Follower interface {
+	After() []string
+}
+
+// 실행 결과 상태
+const (
+	StatusOK      = "성공"
+	StatusPartial = "부분 실패"
+	StatusFailed  = "실패"
+	StatusSkipped = "건너뜀"
+)
+
+// 프로세스 종료 코드. CI와 GUI는 이 값으로 전체 실패와 부분 실패를 구분합니다.
+const (
+	ExitOK      = 0 // 모든 지표 성공
+	ExitError   = 1 // 입력/설정 오류 등으로 실행 자체가 불가능하거나 결과를 저장하지 못함
+	ExitPartial = 2 // 결과는 저장했지만 일부 지표가 실패(또는 부분 실패, 선행 지표 실패로 건너뜀)함
+)
+
+// Result는 지표 하나의 실행 결과입니다.
+type Result struct {
+	Name     string
+	Status   string
+	Err      error
+	Elements int // 조각 문서의 요소 개수
+	Duration time.Duration
+}
+
+// Report는 한 번의 실행에서 모든 지표의 결과를 실행 순서대로 담습니다.
+type Report struct {
+	Results []Result
+}
+
+// Registry는 등록된 지표와 활성화 여부를 관리합니다.
+type Registry struct {
+	metrics  map[string]Metric
+	order    []string // 등록 순서
+	disabled map[string]bool
+}
+
+// Default는 각 지표 패키지가 init()에서 자신을 등록하는 기본 레지스트리입니다.
+var Default = NewRegistry()
+
+// Register는 m을 기본 레지스트리에 등록합니다. 이름이 중복되면 panic합니다(init 시점의 프로그래밍 오류).
+func Register(m Metric) {
+	if err := Default.Register(m); err != nil {
+		panic(err)
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics:  make(map[string]Metric),
+		disabled: make(map[string]bool),
+	}
+}
+
+// Register는 지표를 등록합니다.
+func (r *Registry) Register(m Metric) error {
+	name := strings.TrimSpace(m.Name())
+	if name == "" {
+		return fmt.Errorf("지표 이름이 비어 있습니다")
+	}
+	if _, ok := r.metrics[name]; ok {
+		return fmt.Errorf("이미 등록된 지표입니다: %s", name)
+	}
+	r.metrics[name] = m
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Names는 등록된 지표 이름을 등록 순서대로 반환합니다.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Enable/Disable은 지표의 활성화 여부를 바꿉니다.
+func (r *Registry) Enable(name string) error {
+	if _, ok := r.metrics[name]; !ok {
+		return fmt.Errorf("등록되지 않은 지표입니다: %s", name)
+	}
+	delete(r.disabled, name)
+	return nil
+}
+
+func (r *Registry) Disable(name string) error {
+	if _, ok := r.metrics[name]; !ok {
+		return fmt.Errorf("등록되지 않은 지표입니다: %s", name)
+	}
+	r.disabled[name] = true
+	return nil
+}
+
+// EnableOnly는 names에 포함된 지표만 활성화하고 나머지는 모두 비활성화합니다.
+func (r *Registry) EnableOnly(names []string) error {
+	want := make(map[string]bool)
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if _, ok := r.metrics[n]; !ok {
+			return fmt.Errorf("등록되지 않은 지표입니다: %s", n)
+		}
+		want[n] = true
+	}
+	for _, n := range r.order {
+		r.disabled[n] = !want[n]
+	}
+	return nil
+}
+
+// Configure는 설정 파일/명령행의 지표 목록을 적용합니다.
+// enabled가 비어 있지 않으면 그 지표만 활성화하고, 이어서 disabled의 지표를 비활성화합니다.
+// 적용한 결과로 실행 계획을 세울 수 없으면(활성화된 지표가 비활성화된 지표를 요구하는 경우 등) 오류를 반환합니다.
+func (r *Registry) Configure(enabled, disabled []string) error {
+	if len(enabled) > 0 {
+		if err := r.EnableOnly(enabled); err != nil {
+			return err
+		}
+	}
+	for _, n := range disabled {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if err := r.Disable(n); err != nil {
+			return err
+		}
+	}
+	_, err := r.Plan()
+	return err
+}
+
+// Enabled는 지표가 활성화되어 있는지 반환합니다.
+func (r *Registry) Enabled(name string) bool {
+	_, ok := r.metrics[name]
+	return ok && !r.disabled[name]
+}
+
+// Plan은 활성화된 지표를 의존관계 순서(위상 정렬)로 반환합니다.
+// 순서가 정해지지 않는 지표끼리는 이름 순으로 실행합니다.
+// 등록되지 않았거나 비활성화된 지표를 요구하거나 순환 의존이 있으면 오류를 반환합니다.
+func (r *Registry) Plan() ([]Metric, error) {
+	indeg := make(map[string]int)
+	next := make(map[string][]string)
+	for _, n := range r.order {
+		if !r.Enabled(n) {
+			continue
+		}
+		indeg[n] += 0
+
+		m := r.metrics[n]
+		before := m.Requires()
+		for _, dep := range before {
+			if _, ok := r.metrics[dep]; !ok {
+				return nil, fmt.Errorf("지표 %s가 등록되지 않은 지표 %s를 요구합니다", n, dep)
+			}
+			// 사용자가 끈 선행 지표 때문에 건너뛰면 부분 실패로 보고되므로, 실행 전에 설정 오류로 알립니다.
+			if !r.Enabled(dep) {
+				return nil, fmt.Errorf("지표 %s가 비활성화된 지표 %s를 요구합니다(%s도 활성화하거나 %s를 비활성화하세요)", n, dep, dep, n)
+			}
+		}
+		if f, ok := m.(Follower); ok {
+			before = append(append([]string(nil), before...), f.After()...)
+		}
+
+		seen := make(map[string]bool)
+		for _, dep := range before {
+			// 비활성화된 After 지표는 순서 계산에서 제외합니다(Requires는 위에서 모두 활성화되어 있음을 확인함).
+			if !r.Enabled(dep) || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			indeg[n]++
+			next[dep] = append(next[dep], n)
+		}
+	}
+
+	var ready []string
+	for n, d := range indeg {
+		if d == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	var plan []Metric
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		n := ready[0]
+		ready = ready[1:]
+		plan = append(plan, r.metrics[n])
+		for _, m := range next[n] {
+			indeg[m]--
+			if indeg[m] == 0 {
+				ready = append(ready, m)
+			}
+		}
+	}
+
+	if len(plan) != len(indeg) {
+		var cyclic []string
+		for n, d := range indeg {
+			if d > 0 {
+				cyclic = append(cyclic, n)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("지표 사이에 순환 의존이 있습니다: %s", strings.Join(cyclic, ", "))
+	}
+	return plan, nil
+}
+
+// Run은 활성화된 지표를 cfg로 의존관계 순서대로 실행하고, 각 조각 문서를 main에 병합합니다.
+// 선행 지표가 실패한 지표는 건너뜁니다.
+// onDone이 nil이 아니면 지표 하나가 끝날 때마다 (완료 개수, 전체 개수, 결과)로 호출됩니다.
+func (r *Registry) Run(cfg *Public_data.RunConfig, main *LDI_Model.Document, onDone func(done, total int, res Result)) (Report, error) {
+	var report Report
+
+	plan, err := r.Plan()
+	if err != nil {
+		return report, err
+	}
+
+	ok := make(map[string]bool)
+	for i, m := range plan {
+		res := r.runOne(cfg, main, m, ok)
+		// 부분 실패한 지표의 결과도 주 LDI에 병합되었으므로, 후속 지표는 계속 실행합니다.
+		ok[res.Name] = res.Status == StatusOK || res.Status == StatusPartial
+		report.Results = append(report.Results, res)
+		if onDone != nil {
+			onDone(i+1, len(plan), res)
+		}
+	}
+	return report, nil
+}
+
+func (r *Registry) runOne(cfg *Public_data.RunConfig, main *LDI_Model.Document, m Metric, ok map[string]bool) Result {
+	res := Result{Name: m.Name()}
+
+	for _, dep := range m.Requires() {
+		if !ok[dep] {
+			res.Status = StatusSkipped
+			res.Err = fmt.Errorf("선행 지표 %s가 실패했습니다", dep)
+			return res
+		}
+	}
+
+	start := time.Now()
+	frag, computeErr := m.Compute(cfg)
+	res.Duration = time.Since(start)
+	if computeErr != nil && frag == nil {
+		res.Status = StatusFailed
+		res.Err = computeErr
+		return res
+	}
+
+	if frag != nil {
+		res.Elements = len(frag.Items)
+		var err error
+		if merger, isMerger := m.(Merger); isMerger {
+			err = merger.Merge(main, frag)
+		} else {
+			main.Merge(frag, LDI_Model.MergeOptions{})
+		}
+		if err != nil {
+			res.Status = StatusFailed
+			res.Err = fmt.Errorf("주 LDI 병합 실패: %v", err)
+			return res
+		}
+	}
+
+	if computeErr != nil {
+		res.Status = StatusPartial
+		res.Err = computeErr
+		return res
+	}
+	res.Status = StatusOK
+	return res
+}
+
+// Failed는 실패, 부분 실패 또는 건너뛴 지표가 하나라도 있으면 true를 반환합니다.
+func (rep Report) Failed() bool {
+	for _, r := range rep.Results {
+		if r.Status != StatusOK {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode는 보고서에 맞는 종료 코드(ExitOK 또는 ExitPartial)를 반환합니다.
+func (rep Report) ExitCode() int {
+	if rep.Failed() {
+		return ExitPartial
+	}
+	return ExitOK
+}
+
+// Count는 상태가 status인 지표의 개수를 반환합니다.
+func (rep Report) Count(status string) int {
+	n := 0
+	for _, r := range rep.Results {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Print는 지표별 실행 결과 요약을 w에 출력합니다.
+func (rep Report) Print(w io.Writer) {
+	fmt.Fprintln(w, "========== 지표 실행 결과 ==========")
+	for _, r := range rep.Results {
+		switch r.Status {
+		case StatusOK:
+			fmt.Fprintf(w, "✅ %-6s %s (요소 %d개, %s)\n", r.Name, r.Status, r.Elements, r.Duration.Round(time.Millisecond))
+		case StatusPartial:
+			fmt.Fprintf(w, "⚠️ %-6s %s (요소 %d개, %s): %v\n", r.Name, r.Status, r.Elements, r.Duration.Round(time.Millisecond), r.Err)
+		case StatusSkipped:
+			fmt.Fprintf(w, "⏭️ %-6s %s: %v\n", r.Name, r.Status, r.Err)
+		default:
+			fmt.Fprintf(w, "❌ %-6s %s: %v\n", r.Name, r.Status, r.Err)
+		}
+	}
+	fmt.Fprintf(w, "합계: %d개 중 성공 %d, 부분 실패 %d, 실패 %d, 건너뜀 %d\n",
+		len(rep.Results), rep.Count(StatusOK), rep.Count(StatusPartial), rep.Count(StatusFailed), rep.Count(StatusSkipped))
+}
@@ -0,0 +1,62 @@
+package Metric_Registry
+
+import (
+	"errors"
+	"testing"
+
+	"FCU_Tools/LDI_Model"
+	"FCU_Tools/Public_data"
+)
+
+// fakeMetric은 정해진 오류를 반환하는 테스트용 지표입니다.
+type fakeMetric struct {
+	name     string
+	requires []string
+	err      error
+}
+
+func (m fakeMetric) Name() string       { return m.name }
+func (m fakeMetric) Requires() []string { return m.requires }
+func (m fakeMetric) Compute(*Public_data.RunConfig) (*LDI_Model.Document, error) {
+	return nil, m.err
+}
+
+func newTestRegistry(t *testing.T, swcErr error) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for _, m := range []Metric{
+		fakeMetric{name: "SWC", err: swcErr},
+		fakeMetric{name: "M3", requires: []string{"SWC"}},
+	} {
+		if err := r.Register(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestConfigureRejectsDisabledPrerequisite(t *testing.T) {
+	if err := newTestRegistry(t, nil).Configure([]string{"M3"}, nil); err == nil {
+		t.Error("M3만 활성화했는데 Configure가 오류를 반환하지 않았습니다")
+	}
+	if err := newTestRegistry(t, nil).Configure(nil, []string{"SWC"}); err == nil {
+		t.Error("SWC를 비활성화했는데 Configure가 오류를 반환하지 않았습니다")
+	}
+	if err := newTestRegistry(t, nil).Configure(nil, []string{"M3"}); err != nil {
+		t.Errorf("M3만 비활성화: %v", err)
+	}
+}
+
+func TestRunSkipsAfterFailedPrerequisite(t *testing.T) {
+	r := newTestRegistry(t, errors.New("asw.csv 없음"))
+	report, err := r.Run(&Public_data.RunConfig{}, LDI_Model.New(), nil)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Results) != 2 || report.Results[0].Status != StatusFailed || report.Results[1].Status != StatusSkipped {
+		t.Fatalf("결과 = %+v, want SWC 실패, M3 건너뜀", report.Results)
+	}
+	if got := report.ExitCode(); got != ExitPartial {
+		t.Errorf("ExitCode = %d, want %d", got, ExitPartial)
+	}
+}
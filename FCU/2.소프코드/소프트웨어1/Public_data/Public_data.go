@@ -2,102 +2,375 @@
 package Public_data
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
-var HierarchyTable [][]string
+// ASWColumns는 asw.csv에서 각 정보를 찾을 헤더 이름(별칭) 목록입니다.
+// 헤더는 대소문자, 공백, 기호(/, _, -)를 무시하고 비교하므로 "DE/OP", "de_op", "DEOP"는 같은 열로 봅니다.
+type ASWColumns struct {
+	Component     []string `json:"component"`      // 컴포넌트 이름
+	ASIL          []string `json:"asil"`           // ASIL 등급(A/B/C/D)
+	PortType      []string `json:"port_type"`      // P / R
+	InterfaceType []string `json:"interface_type"` // CS / SR
+	DEOP          []string `json:"de_op"`          // 연결을 묶는 DE/OP 이름
 
-// ConnectorFilePath에는 asw.csv의 경로가 기록되어 있습니다.
-var ConnectorFilePath string
+	// 아래 열은 선택 사항입니다. 없어도 오류가 아니며, N:M 그룹을 이름으로 짝지을 때만 사용합니다.
+	Port      []string `json:"port"`      // 포트 이름
+	Interface []string `json:"interface"` // 인터페이스 이름
+}
 
-// OutputDir에는 최중 출력 경로가 기록되어 있습니다.
-var OutputDir string
+// DefaultASWColumns는 기존 asw.csv 양식의 헤더 이름입니다.
+var DefaultASWColumns = ASWColumns{
+	Component:     []string{"Component", "Component Name", "SWC"},
+	ASIL:          []string{"ASIL"},
+	PortType:      []string{"PortType"},
+	InterfaceType: []string{"InterfaceType"},
+	DEOP:          []string{"DE/OP", "DataElement/Operation"},
+	Port:          []string{"Port", "Port Name"},
+	Interface:     []string{"Interface", "Interface Name"},
+}
 
-// ComplexityJsonPath에는 complexity.json의 경로가 기록되어 있습니다.
-var M2ComplexityJsonPath string
+// asw.csv의 한 DE/OP에 P 포트와 R 포트가 모두 2개 이상 있는(N:M) 그룹을 처리하는 방식입니다.
+const (
+	NMPolicyCrossProduct = "cross"     // 모든 P × R 조합을 연결합니다(기본값).
+	NMPolicyPairByName   = "pair_name" // Port 이름(없으면 Interface 이름)이 같은 P와 R만 연결합니다.
+	NMPolicySkip         = "skip"      // 그룹 전체를 분석에서 제외합니다(이전 동작).
+)
 
-// RqExcelPath에는 rq_versus_component.xlsx의 경로가 기록되어 있습니다.
-var M2RqExcelPath string
+// M1DepthUnlimited를 m1.max_depth에 지정하면 M1이 모델의 가장 깊은 레벨까지 분석합니다.
+const M1DepthUnlimited = 0
+
+// M1 S-R 포트의 종류입니다. 블록에 선이 들어가는 포트(in/trigger/enable/ifaction)와 포트 블록의 설정으로 구분합니다.
+const (
+	M1PortKindData         = "data"          // 일반 데이터 포트(Inport/Outport, in:N/out:N)
+	M1PortKindBusElement   = "bus_element"   // 버스 요소 포트(IsBusElementPort=on, BusSelector/BusCreator와 직접 연결)
+	M1PortKindTrigger      = "trigger"       // 트리거 포트(#trigger)
+	M1PortKindEnable       = "enable"        // 활성화 포트(#enable)
+	M1PortKindFunctionCall = "function_call" // 함수 호출 트리거(TriggerType=function-call, OutputFunctionCall=on)
+	M1PortKindAction       = "action"        // If/Switch Case의 Action 포트(#ifaction)
+)
 
-// M3component_infoxlsxPath에는 component_info.xlsx의 경로가 기록되어 있습니다.
-var M3component_infoxlsxPath string
+// M1PortKinds는 M1 S-R 포트 종류의 목록(출력 순서)입니다.
+var M1PortKinds = []string{M1PortKindData, M1PortKindBusElement, M1PortKindTrigger, M1PortKindEnable, M1PortKindFunctionCall, M1PortKindAction}
 
-//여기에는 M2~M6 각각의 출력 경로가 기록되어 있습니다.
-var M2OutputlPath string
-var M3OutputlPath string
-var M4OutputlPath string
-var M5OutputlPath string
-var M6OutputlPath string
+// IsM1PortKind는 kind가 M1PortKinds 중 하나인지 반환합니다.
+func IsM1PortKind(kind string) bool {
+	for _, k := range M1PortKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
 
-// SetConnectorFilePath를 통해 asw.csv 경로를 설정합니다.
-func SetConnectorFilePath(path string) {
-	ConnectorFilePath = path
+// M1LevelRule은 M1 계층 분석에서 한 레벨에 적용하는 규칙입니다.
+// 규칙은 Level부터 다음 규칙의 Level 직전까지 적용되므로, 마지막 규칙은 그보다 깊은 모든 레벨에 적용됩니다.
+type M1LevelRule struct {
+	Level             int      `json:"level"`               // 규칙이 적용되기 시작하는 레벨(1부터)
+	BlockTypes        []string `json:"block_types"`         // 노드로 셀 BlockType(비어 있으면 모든 BlockType)
+	ExcludeBlockTypes []string `json:"exclude_block_types"` // 노드에서 제외할 BlockType
+	SkipEmptyPorts    bool     `json:"skip_empty_ports"`    // Ports=[]이거나 PortCounts가 비어 있는 블록(초기화된 SubSystem)을 제외
+	CSPortWeight      float64  `json:"cs_port_weight"`      // C-S 포트 1개의 가중치(0이면 L1은 m1.cs_port_weight, 그 외 레벨은 1)
+	VirtualPorts      bool     `json:"virtual_ports"`       // 블록-블록 직접 연결마다 양쪽 블록에 가상 Inport/Outport를 만들어 포트로 셈
 
+	// PortKindWeights는 S-R 포트 종류(M1PortKinds)별 포트 1개의 가중치입니다. 지정하지 않은 종류는 1입니다.
+	PortKindWeights map[string]float64 `json:"port_kind_weights"`
 }
-func SetM2M3FilePath(path string) {
-	M2ComplexityJsonPath = filepath.Join(path, "complexity.json")
-	M2RqExcelPath = filepath.Join(path, "rq_versus_component.csv")
-	M3component_infoxlsxPath = filepath.Join(path, "component_info.csv")
+
+// PortKindWeight는 kind 종류의 S-R 포트 1개에 주는 가중치를 반환합니다(지정되지 않았으면 1).
+func (r M1LevelRule) PortKindWeight(kind string) float64 {
+	if w, ok := r.PortKindWeights[kind]; ok {
+		return w
+	}
+	return 1
 }
 
-// 터미널에 asw.csv 파일의 경로를 입력하고, 해당 경로를 ConnectorFilePath에 기록합니다.
-func InitConnectorFilePathFromUser() error {
-	var dir string
-	fmt.Print("asw.csv 등 파일의 경로를 입력하세요:")
-	if _, err := fmt.Scanln(&dir); err != nil {
-		return fmt.Errorf("읽기 실패: %v", err)
+// CountsBlockType은 blockType의 블록을 이 레벨의 노드로 세는지 반환합니다.
+func (r M1LevelRule) CountsBlockType(blockType string) bool {
+	for _, t := range r.ExcludeBlockTypes {
+		if t == blockType {
+			return false
+		}
+	}
+	if len(r.BlockTypes) == 0 {
+		return true
+	}
+	for _, t := range r.BlockTypes {
+		if t == blockType {
+			return true
+		}
 	}
+	return false
+}
 
-	csvPath := filepath.Join(dir, "asw.csv")
-	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
-		return fmt.Errorf("asw.csv 파일을 찾을 수 없습니다: %s", csvPath)
+// M1LevelRules는 Level 오름차순으로 정렬된 M1 레벨 규칙 목록입니다.
+type M1LevelRules []M1LevelRule
+
+// ForLevel은 level에 적용할 규칙(Level이 level 이하인 규칙 중 가장 깊은 것)을 반환합니다.
+func (rs M1LevelRules) ForLevel(level int) M1LevelRule {
+	var rule M1LevelRule
+	for _, r := range rs {
+		if r.Level > level {
+			break
+		}
+		rule = r
 	}
+	return rule
+}
+
+// WithVirtualPorts는 fromLevel 이후의 모든 레벨에서 VirtualPorts를 켠 복사본을 반환합니다.
+// fromLevel에서 시작하는 규칙이 없으면 그 레벨에 적용되던 규칙을 복사하여 fromLevel 규칙으로 추가합니다.
+func (rs M1LevelRules) WithVirtualPorts(fromLevel int) M1LevelRules {
+	out := append(M1LevelRules(nil), rs...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Level < out[j].Level })
+
+	found := false
+	for _, r := range out {
+		if r.Level == fromLevel {
+			found = true
+			break
+		}
+	}
+	if !found {
+		r := out.ForLevel(fromLevel)
+		r.Level = fromLevel
+		out = append(out, r)
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Level < out[j].Level })
+	}
+
+	for i := range out {
+		if out[i].Level >= fromLevel {
+			out[i].VirtualPorts = true
+		}
+	}
+	return out
+}
+
+// M1PortBlockTypes는 블록이 아니라 포트를 나타내는 BlockType입니다(L3 이후 노드에서 제외합니다).
+// InportShadow는 같은 Inport의 복제이고, TriggerPort/EnablePort/ActionPort는 부모 SubSystem의 제어 입력입니다.
+var M1PortBlockTypes = []string{"Inport", "Outport", "InportShadow", "TriggerPort", "EnablePort", "ActionPort"}
+
+// DefaultM1LevelRules는 기본 레벨 규칙입니다.
+// L1: 유효한 SubSystem만, L2: 모든 SubSystem, L3 이후: 포트 블록(M1PortBlockTypes)이 아닌 모든 Block
+// 이전에는 L3 이후에서 Inport/Outport만 제외하여 InportShadow/TriggerPort/EnablePort/ActionPort도 노드로 셌습니다.
+// 이전 결과와 맞춰야 하면 m1.levels의 level 3 규칙에 "exclude_block_types": ["Inport", "Outport"]를 지정합니다.
+// 라이브러리 링크(Reference)와 모델 참조(ModelReference)는 SubSystem과 같이 취급합니다.
+var DefaultM1LevelRules = M1LevelRules{
+	{Level: 1, BlockTypes: []string{"SubSystem", "Reference", "ModelReference"}, SkipEmptyPorts: true},
+	{Level: 2, BlockTypes: []string{"SubSystem", "Reference", "ModelReference"}},
+	{Level: 3, ExcludeBlockTypes: M1PortBlockTypes},
+}
 
-	SetConnectorFilePath(csvPath)
-	SetM2M3FilePath(dir)
+// M1Discovery는 M1이 모델 디렉터리에서 분석할 모델 파일을 찾는 규칙입니다.
+// Include가 비어 있으면 기존 배치(<모델 디렉터리>/<Name>/<Name>.slx 또는 .mdl)만 찾습니다.
+// 패턴은 모델 디렉터리 기준 상대 경로("/" 구분)에 대한 glob이며, "**"는 0개 이상의 폴더와 일치합니다.
+type M1Discovery struct {
+	Include []string `json:"include"` // 예: ["**/*.slx", "**/*.mdl"]
+	Exclude []string `json:"exclude"` // 예: ["**/libs/**", "**/*_old.slx"]
+
+	// SWCNameMap은 모델 파일(상대 경로, 파일 이름 또는 확장자를 뺀 파일 이름)에서 SWC 이름으로의 매핑입니다.
+	SWCNameMap map[string]string `json:"swc_name_map"`
+
+	// SWCNameRegex가 확장자를 뺀 파일 이름과 일치하면 "swc" 이름의 그룹(없으면 첫 번째 그룹)을 SWC 이름으로 씁니다.
+	// 예: `^(.+?)_v\d+(_\d+)*$`는 "TurnLight_v1_2"를 "TurnLight"로 바꿉니다.
+	SWCNameRegex string `json:"swc_name_regex"`
+}
+
+// validate는 패턴과 정규식의 문법을 검사합니다.
+func (d M1Discovery) validate() error {
+	for _, pat := range append(append([]string(nil), d.Include...), d.Exclude...) {
+		for _, seg := range strings.Split(strings.Trim(pat, "/"), "/") {
+			if seg == "**" {
+				continue
+			}
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("m1.discovery 패턴이 올바르지 않습니다 %q: %v", pat, err)
+			}
+		}
+	}
+	if d.SWCNameRegex != "" {
+		if _, err := regexp.Compile(d.SWCNameRegex); err != nil {
+			return fmt.Errorf("m1.discovery.swc_name_regex가 올바르지 않습니다: %v", err)
+		}
+	}
 	return nil
 }
 
-// InitOutputDirectoryWithConnectorDir는 Output 경로를 초기화하고,
-// 입력 디렉터리에서 asw.csv 경로를 기록합니다.
-func InitOutputDirectoryWithConnectorDir(dir string) error {
+// WithAliases는 c의 기본 별칭 뒤에 extra의 별칭을 덧붙인 새 ASWColumns를 반환합니다.
+func (c ASWColumns) WithAliases(extra ASWColumns) ASWColumns {
+	join := func(a, b []string) []string {
+		return append(append([]string(nil), a...), b...)
+	}
+	return ASWColumns{
+		Component:     join(c.Component, extra.Component),
+		ASIL:          join(c.ASIL, extra.ASIL),
+		PortType:      join(c.PortType, extra.PortType),
+		InterfaceType: join(c.InterfaceType, extra.InterfaceType),
+		DEOP:          join(c.DEOP, extra.DEOP),
+		Port:          join(c.Port, extra.Port),
+		Interface:     join(c.Interface, extra.Interface),
+	}
+}
+
+// RunConfig는 한 번의 실행에 필요한 입력/출력 경로를 담습니다.
+// 전역 변수 대신 이 값을 모든 단계에 전달하므로, 라이브러리로 사용하거나
+// 같은 프로세스에서 여러 번(또는 병렬로) 실행하고, 현재 디렉터리가 아닌 곳에 결과를 쓸 수 있습니다.
+type RunConfig struct {
+	// WorkDir는 중간 산출물(M1/build, M2/output ...)을 만드는 기준 디렉터리입니다.
+	WorkDir string
+
+	// OutputDir에는 최종 출력 경로(result.ldi.xml 위치)가 기록되어 있습니다.
+	OutputDir string
+
+	// ConnectorFilePath에는 asw.csv의 경로가 기록되어 있습니다.
+	ConnectorFilePath string
+
+	// M2ComplexityJsonPath에는 complexity.json의 경로가 기록되어 있습니다.
+	M2ComplexityJsonPath string
+
+	// M2RqExcelPath에는 rq_versus_component.csv의 경로가 기록되어 있습니다.
+	M2RqExcelPath string
+
+	// M3component_infoxlsxPath에는 component_info.csv의 경로가 기록되어 있습니다.
+	M3component_infoxlsxPath string
+
+	// ModelDir에는 M1에서 분석할 모델 폴더 경로가 기록되어 있습니다. 비어 있으면 M1이 실행 중에 입력을 받습니다.
+	ModelDir string
+
+	// M1MaxDepth는 M1 계층 분석의 최대 깊이입니다. M1DepthUnlimited(0)이면 모델의 가장 깊은 레벨까지 분석합니다.
+	M1MaxDepth int
+
+	// M1LevelRules는 레벨별로 노드로 셀 BlockType, 빈 포트 필터 적용 여부, 포트 가중치를 정합니다.
+	M1LevelRules M1LevelRules
+
+	// M1CSPortWeight는 M1 계산 시 C-S 포트 1개에 주는 가중치입니다(일반 포트는 1).
+	M1CSPortWeight float64
+
+	// M1Workers는 M1에서 모델을 병렬로 분석할 워커 수입니다. 0이면 CPU 수만큼 사용합니다.
+	M1Workers int
+
+	// M1ExtractToDisk가 true이면 M1이 slx를 <WorkDir>/M1/build에 풀어서 분석합니다(디버그용). 기본은 메모리에서 바로 읽습니다.
+	M1ExtractToDisk bool
+
+	// M1DumpTxt/M1DumpJSON이 true이면 M1이 분석한 블록 트리를 <WorkDir>/M1/output/txt에 txt/JSON으로도 출력합니다.
+	M1DumpTxt  bool
+	M1DumpJSON bool
+
+	// M1Discovery는 모델 디렉터리에서 분석할 모델 파일을 찾는 규칙과 SWC 이름 매핑입니다.
+	M1Discovery M1Discovery
+
+	// M1ConsistencyProperties가 true이면 asw.csv 컴포넌트와 모델 목록의 불일치를 LDI 속성(m1.consistency)으로도 기록합니다.
+	// 불일치 보고서(<WorkDir>/M1/output/model_consistency.txt)는 항상 작성합니다.
+	M1ConsistencyProperties bool
+
+	// ASWColumns는 asw.csv의 열을 찾을 헤더 이름(별칭)입니다.
+	ASWColumns ASWColumns
+
+	// NMPolicy는 asw.csv의 N:M 그룹 처리 방식(NMPolicyCrossProduct, NMPolicyPairByName, NMPolicySkip)입니다.
+	NMPolicy string
+
+	// EnabledMetrics가 비어 있지 않으면 이 지표만 실행합니다. DisabledMetrics의 지표는 실행하지 않습니다.
+	// 활성화된 지표가 요구하는 선행 지표(Requires, 예: M1~M6의 SWC)를 끄면 설정 오류입니다.
+	EnabledMetrics  []string
+	DisabledMetrics []string
+}
+
+// NewRunConfig는 workDir를 기준으로 기본 경로(Output, M1~M6 작업 폴더)와 기본 분석 옵션을 설정한 RunConfig를 생성합니다.
+// 입력 파일 경로는 SetInputDir 또는 InitConnectorFilePathFromUser로 설정합니다.
+func NewRunConfig(workDir string) *RunConfig {
+	return &RunConfig{
+		WorkDir:        workDir,
+		OutputDir:      filepath.Join(workDir, "Output"),
+		M1MaxDepth:     3,
+		M1CSPortWeight: 1.2,
+		M1LevelRules:   DefaultM1LevelRules,
+		ASWColumns:     DefaultASWColumns,
+		NMPolicy:       NMPolicyCrossProduct,
+	}
+}
+
+// NewRunConfigFromCwd는 현재 작업 디렉터리를 기준으로 RunConfig를 생성합니다(기존 동작과 동일한 경로).
+func NewRunConfigFromCwd() (*RunConfig, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("현재 작업 디렉터리를 가져올 수 없습니다: %v", err)
+	}
+	return NewRunConfig(wd), nil
+}
+
+// SetInputDir는 dir에 asw.csv가 있는지 확인하고, asw.csv 및 M2/M3 입력 파일 경로를 기록합니다.
+func (c *RunConfig) SetInputDir(dir string) error {
 	if strings.TrimSpace(dir) == "" {
 		return fmt.Errorf("입력 경로가 비어 있습니다")
 	}
 
-	baseDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("출력 디렉터리 초기화에 실패했습니다: 현재 작업 디렉터리를 가져올 수 없습니다: %v", err)
+	csvPath := filepath.Join(dir, "asw.csv")
+	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
+		return fmt.Errorf("asw.csv 파일을 찾을 수 없습니다: %s", csvPath)
 	}
 
-	outputPath := filepath.Join(baseDir, "Output")
-	OutputDir = outputPath
+	c.ConnectorFilePath = csvPath
+	c.M2ComplexityJsonPath = filepath.Join(dir, "complexity.json")
+	c.M2RqExcelPath = filepath.Join(dir, "rq_versus_component.csv")
+	c.M3component_infoxlsxPath = filepath.Join(dir, "component_info.csv")
+	return nil
+}
 
-	if _, err := os.Stat(outputPath); err == nil {
-		if err := os.RemoveAll(outputPath); err != nil {
+// 터미널에 asw.csv 파일의 경로를 입력하고, 해당 경로를 ConnectorFilePath에 기록합니다.
+func (c *RunConfig) InitConnectorFilePathFromUser() error {
+	var dir string
+	fmt.Print("asw.csv 등 파일의 경로를 입력하세요:")
+	if _, err := fmt.Scanln(&dir); err != nil {
+		return fmt.Errorf("읽기 실패: %v", err)
+	}
+	return c.SetInputDir(dir)
+}
+
+// InitOutputDirectory는 OutputDir를 비우고 새로 생성합니다.
+func (c *RunConfig) InitOutputDirectory() error {
+	if strings.TrimSpace(c.OutputDir) == "" {
+		return fmt.Errorf("출력 디렉터리 초기화에 실패했습니다: OutputDir가 비어 있습니다")
+	}
+
+	// Output 디렉터리가 존재하면 삭제합니다
+	if _, err := os.Stat(c.OutputDir); err == nil {
+		if err := os.RemoveAll(c.OutputDir); err != nil {
 			return fmt.Errorf("출력 디렉터리 초기화에 실패했습니다: 이전 Output 디렉터리 삭제에 실패했습니다: %v", err)
 		}
 	}
 
-	if err := os.Mkdir(outputPath, 0755); err != nil {
+	// Output 디렉터리 생성
+	if err := os.MkdirAll(c.OutputDir, 0755); err != nil {
 		return fmt.Errorf("출력 디렉터리 초기화에 실패했습니다: Output 디렉터리 생성에 실패했습니다: %v", err)
 	}
+	return nil
+}
 
-	csvPath := filepath.Join(dir, "asw.csv")
-	if _, err := os.Stat(csvPath); os.IsNotExist(err) {
-		return fmt.Errorf("asw.csv 파일을 찾을 수 없습니다: %s", csvPath)
-	}
+// MetricWorkDir는 지표별 작업 폴더(<WorkDir>/<name>, 예: M1, M2)를 반환합니다.
+func (c *RunConfig) MetricWorkDir(name string) string {
+	return filepath.Join(c.WorkDir, name)
+}
 
-	SetConnectorFilePath(csvPath)
-	SetM2M3FilePath(dir)
-	return nil
+// MetricOutputDir는 M2~M6 지표의 출력 폴더(<WorkDir>/<name>/output)를 반환합니다.
+func (c *RunConfig) MetricOutputDir(name string) string {
+	return filepath.Join(c.MetricWorkDir(name), "output")
+}
+
+// ResultPath는 최종 결과 파일(OutputDir/result.ldi.xml)의 경로를 반환합니다.
+func (c *RunConfig) ResultPath() string {
+	return filepath.Join(c.OutputDir, "result.ldi.xml")
 }
 
 // // SetM2InputDir는 complexity.json 및 rq_versus_component.xlsx가 포함된 사용자 입력의 디렉토리 경로를 설정합니다.
-// func SetM2InputDir(dir string) error {
+// func (c *RunConfig) SetM2InputDir(dir string) error {
 // 	complexity := filepath.Join(dir, "complexity.json")
 // 	rqExcel := filepath.Join(dir, "rq_versus_component.xlsx")
 
@@ -108,40 +381,222 @@ func InitOutputDirectoryWithConnectorDir(dir string) error {
 // 		return fmt.Errorf("rq_versus_component.xlsx 을 찾을 수 없습니다.: %s", rqExcel)
 // 	}
 
-// 	M2ComplexityJsonPath = complexity
-// 	M2RqExcelPath = rqExcel
+// 	c.M2ComplexityJsonPath = complexity
+// 	c.M2RqExcelPath = rqExcel
 // 	return nil
 // }
 
-// Output 경로를 초기화하고, asw.csv 경로를 기록하는 함수 호출
-func InitOutputDirectory() {
-	//루트 디렉터리를 받음
-	baseDir, err := os.Getwd()
+// configFile은 설정 파일(JSON)의 형식입니다. 경로가 상대 경로이면 설정 파일이 있는 디렉터리를 기준으로 합니다.
+// asw_columns의 이름은 기본 헤더 이름(DefaultASWColumns)에 별칭으로 추가됩니다.
+//
+//	{
+//	  "work_dir": ".",
+//	  "output_dir": "Output",
+//	  "input_dir": "input",
+//	  "inputs": { "asw_csv": "input/asw.csv", "component_info_csv": "..." },
+//	  "model_dir": "models",
+//	  "m1": { "max_depth": 3, "cs_port_weight": 1.2, "workers": 0, "extract_to_disk": false, "dump_txt": false, "dump_json": false,
+//	          "levels": [ { "level": 1, "block_types": ["SubSystem"], "skip_empty_ports": true, "port_kind_weights": { "trigger": 1.5 } }, ... ],
+//	          "discovery": { "include": ["**/*.slx"], "exclude": ["**/libs/**"], "swc_name_map": { "A_v2.slx": "A" }, "swc_name_regex": "" },
+//	          "consistency_properties": false },
+//	  "asw_columns": { "component": ["SWC Name"], "de_op": ["Signal/Operation"] },
+//	  "swc": { "nm_policy": "cross" },
+//	  "metrics": { "enabled": ["SWC", "M1"], "disabled": [] }
+//	}
+type configFile struct {
+	WorkDir   string `json:"work_dir"`
+	OutputDir string `json:"output_dir"`
+	InputDir  string `json:"input_dir"`
+	Inputs    struct {
+		ASWCsv               string `json:"asw_csv"`
+		ComplexityJson       string `json:"complexity_json"`
+		RqVersusComponentCsv string `json:"rq_versus_component_csv"`
+		ComponentInfoCsv     string `json:"component_info_csv"`
+	} `json:"inputs"`
+	ModelDir string `json:"model_dir"`
+	M1       struct {
+		MaxDepth      int           `json:"max_depth"`
+		CSPortWeight  float64       `json:"cs_port_weight"`
+		ExtractToDisk bool          `json:"extract_to_disk"`
+		Workers       int           `json:"workers"`
+		DumpTxt       bool          `json:"dump_txt"`
+		DumpJSON      bool          `json:"dump_json"`
+		Levels        []M1LevelRule `json:"levels"`
+		Discovery     M1Discovery   `json:"discovery"`
+
+		ConsistencyProperties bool `json:"consistency_properties"`
+	} `json:"m1"`
+	ASWColumns ASWColumns `json:"asw_columns"`
+	SWC        struct {
+		NMPolicy string `json:"nm_policy"`
+	} `json:"swc"`
+	Metrics struct {
+		Enabled  []string `json:"enabled"`
+		Disabled []string `json:"disabled"`
+	} `json:"metrics"`
+}
+
+// LoadConfigFile은 설정 파일(JSON)을 읽어 RunConfig를 생성합니다.
+// 설정 파일에 없는 항목은 NewRunConfig의 기본값을 사용하며, work_dir가 없으면 현재 작업 디렉터리를 기준으로 합니다.
+// 알 수 없는 키가 있으면 오타로 보고 오류를 반환합니다.
+func LoadConfigFile(path string) (*RunConfig, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Println("출력 디렉터리 초기화에 실패했습니다: 현재 작업 디렉터리를 가져올 수 없습니다.", err)
-		return
+		return nil, fmt.Errorf("설정 파일 읽기 실패 [%s]: %v", path, err)
 	}
-	//루트 디렉터리 + Output를 통해 출력경로를 만듬.
-	outputPath := filepath.Join(baseDir, "Output")
-	OutputDir = outputPath
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("설정 파일 경로 확인 실패 [%s]: %v", path, err)
+	}
+	baseDir := filepath.Dir(absPath)
 
-	// Output 디렉터리가 존재하면 삭제합니다
-	if _, err := os.Stat(outputPath); err == nil {
-		if err := os.RemoveAll(outputPath); err != nil {
-			fmt.Println("출력 디렉터리 초기화에 실패했습니다: 이전 Output 디렉터리 삭제에 실패했습니다:", err)
-			return
+	// 기본값을 먼저 채운 뒤, 설정 파일에 있는 항목만 덮어씁니다.
+	defaults := NewRunConfig("")
+	var fc configFile
+	fc.M1.MaxDepth = defaults.M1MaxDepth
+	fc.M1.CSPortWeight = defaults.M1CSPortWeight
+	fc.SWC.NMPolicy = defaults.NMPolicy
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return nil, fmt.Errorf("설정 파일 파싱 실패 [%s]: %v", path, err)
+	}
+
+	resolve := func(p string) string {
+		p = strings.TrimSpace(p)
+		if p == "" || filepath.IsAbs(p) {
+			return p
 		}
+		return filepath.Join(baseDir, p)
 	}
 
-	// Output 디렉터리 생성
-	if err := os.Mkdir(outputPath, 0755); err != nil {
-		fmt.Println("출력 디렉터리 초기화에 실패했습니다: Output 디렉터리 생성에 실패했습니다:", err)
-		return
+	var cfg *RunConfig
+	if fc.WorkDir != "" {
+		cfg = NewRunConfig(resolve(fc.WorkDir))
+	} else {
+		cfg, err = NewRunConfigFromCwd()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fc.OutputDir != "" {
+		cfg.OutputDir = resolve(fc.OutputDir)
 	}
 
-	// asw.csv 경로 입력 및 기록
-	if err := InitConnectorFilePathFromUser(); err != nil {
-		fmt.Println("asw.csv 경로 설정에 실패했습니다:", err)
-		return
+	if fc.InputDir != "" {
+		if err := cfg.SetInputDir(resolve(fc.InputDir)); err != nil {
+			return nil, fmt.Errorf("설정 파일 오류 [%s]: %v", path, err)
+		}
+	}
+	// inputs의 개별 파일 경로는 input_dir로 정해진 경로보다 우선합니다.
+	if fc.Inputs.ASWCsv != "" {
+		cfg.ConnectorFilePath = resolve(fc.Inputs.ASWCsv)
+	}
+	if fc.Inputs.ComplexityJson != "" {
+		cfg.M2ComplexityJsonPath = resolve(fc.Inputs.ComplexityJson)
+	}
+	if fc.Inputs.RqVersusComponentCsv != "" {
+		cfg.M2RqExcelPath = resolve(fc.Inputs.RqVersusComponentCsv)
+	}
+	if fc.Inputs.ComponentInfoCsv != "" {
+		cfg.M3component_infoxlsxPath = resolve(fc.Inputs.ComponentInfoCsv)
+	}
+
+	cfg.ModelDir = resolve(fc.ModelDir)
+	cfg.M1MaxDepth = fc.M1.MaxDepth
+	cfg.M1CSPortWeight = fc.M1.CSPortWeight
+	if len(fc.M1.Levels) > 0 {
+		cfg.M1LevelRules = fc.M1.Levels
+	}
+	cfg.M1ExtractToDisk = fc.M1.ExtractToDisk
+	cfg.M1Workers = fc.M1.Workers
+	cfg.M1DumpTxt = fc.M1.DumpTxt
+	cfg.M1DumpJSON = fc.M1.DumpJSON
+	cfg.M1Discovery = fc.M1.Discovery
+	cfg.M1ConsistencyProperties = fc.M1.ConsistencyProperties
+	cfg.ASWColumns = DefaultASWColumns.WithAliases(fc.ASWColumns)
+	cfg.NMPolicy = fc.SWC.NMPolicy
+	cfg.EnabledMetrics = fc.Metrics.Enabled
+	cfg.DisabledMetrics = fc.Metrics.Disabled
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("설정 파일 오류 [%s]: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate는 분석 옵션 값이 올바른지 검사합니다(입력 파일 존재 여부와 asw.csv 헤더는 각 단계에서 확인합니다).
+func (c *RunConfig) Validate() error {
+	if c.M1MaxDepth < 0 {
+		return fmt.Errorf("m1.max_depth는 0(무제한) 이상이어야 합니다: %d", c.M1MaxDepth)
+	}
+	if c.M1CSPortWeight <= 0 {
+		return fmt.Errorf("m1.cs_port_weight는 0보다 커야 합니다: %v", c.M1CSPortWeight)
+	}
+	if err := validateM1LevelRules(c.M1LevelRules); err != nil {
+		return err
+	}
+	if c.M1Workers < 0 {
+		return fmt.Errorf("m1.workers는 0(CPU 수) 이상이어야 합니다: %d", c.M1Workers)
+	}
+	if err := c.M1Discovery.validate(); err != nil {
+		return err
+	}
+	switch c.NMPolicy {
+	case NMPolicyCrossProduct, NMPolicyPairByName, NMPolicySkip:
+	default:
+		return fmt.Errorf("swc.nm_policy는 %s, %s, %s 중 하나여야 합니다: %q", NMPolicyCrossProduct, NMPolicyPairByName, NMPolicySkip, c.NMPolicy)
+	}
+	return nil
+}
+
+// validateM1LevelRules는 모든 레벨에 규칙이 적용되도록 L1 규칙이 있는지, 레벨이 중복되지 않는지 검사합니다.
+func validateM1LevelRules(rules M1LevelRules) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("m1.levels가 비어 있습니다")
+	}
+	seen := make(map[int]bool)
+	for _, r := range rules {
+		if r.Level < 1 {
+			return fmt.Errorf("m1.levels의 level은 1 이상이어야 합니다: %d", r.Level)
+		}
+		if seen[r.Level] {
+			return fmt.Errorf("m1.levels에 level %d가 중복되어 있습니다", r.Level)
+		}
+		seen[r.Level] = true
+		if r.CSPortWeight < 0 {
+			return fmt.Errorf("m1.levels의 cs_port_weight는 0 이상이어야 합니다(level %d): %v", r.Level, r.CSPortWeight)
+		}
+		for kind, w := range r.PortKindWeights {
+			if !IsM1PortKind(kind) {
+				return fmt.Errorf("m1.levels의 port_kind_weights에 알 수 없는 포트 종류가 있습니다(level %d): %q (%s)", r.Level, kind, strings.Join(M1PortKinds, ", "))
+			}
+			if w < 0 {
+				return fmt.Errorf("m1.levels의 port_kind_weights는 0 이상이어야 합니다(level %d, %s): %v", r.Level, kind, w)
+			}
+		}
+	}
+	if !seen[1] {
+		return fmt.Errorf("m1.levels에 level 1 규칙이 없습니다")
+	}
+	return nil
+}
+
+// ResolvedM1LevelRules는 M1LevelRules를 Level 순으로 정렬하고, 지정되지 않은(0) C-S 포트 가중치를
+// 채운 복사본을 반환합니다(L1은 M1CSPortWeight, 그 외 레벨은 1).
+func (c *RunConfig) ResolvedM1LevelRules() M1LevelRules {
+	rules := append(M1LevelRules(nil), c.M1LevelRules...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Level < rules[j].Level })
+	for i := range rules {
+		if rules[i].CSPortWeight != 0 {
+			continue
+		}
+		if rules[i].Level == 1 {
+			rules[i].CSPortWeight = c.M1CSPortWeight
+		} else {
+			rules[i].CSPortWeight = 1
+		}
 	}
+	return rules
 }
@@ -3,266 +3,409 @@ package SWC_Dependence
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"unicode"
 
 	"FCU_Tools/LDI_Create"
+	"FCU_Tools/LDI_Model"
+	"FCU_Tools/Metric_Registry"
+	"FCU_Tools/Public_data"
 )
 
+// Metric은 SWC 간 의존관계 분석을 Metric_Registry에 등록하기 위한 구현입니다.
+// 주 LDI의 요소(컴포넌트)와 uses를 만드는 단계이므로, M1~M6은 모두 이 단계를 선행 조건으로 요구합니다.
+type Metric struct{}
+
+func init() {
+	Metric_Registry.Register(Metric{})
+}
+
+func (Metric) Name() string       { return "SWC" }
+func (Metric) Requires() []string { return nil }
+
+func (Metric) Compute(cfg *Public_data.RunConfig) (*LDI_Model.Document, error) {
+	return AnalyzeSWCDependencies(cfg.ConnectorFilePath, cfg.ASWColumns, cfg.NMPolicy)
+}
+
+// Merge: 의존관계 문서의 요소는 주 LDI에 없으므로 모두 새로 추가합니다.
+func (Metric) Merge(main, frag *LDI_Model.Document) error {
+	main.Merge(frag, LDI_Model.MergeOptions{CreateMissing: true})
+	return nil
+}
+
 // 이 구조체는 의존 관계 정보를 저장합니다.
 type DependencyInfo struct {
 	To            string   //의존 대상 컴포넌트명
 	Count         int	   //의존 강도(연결된 링크/선의 개수)
-	InterfaceType string   //P 포트인지 R 포트인지(제공/수신 여부)를 기록합니다.
+	InterfaceType string   //인터페이스 타입(asw.csv의 InterfaceType 값, 예: S-R / C-S)
+	Direction     string   //의존 방향(DirectionProviderToReceiver 또는 DirectionReceiverToProvider)
+}
+
+// 의존 방향
+//   - S-R: 송신자(P 포트)의 데이터를 수신자(R 포트)가 받으므로 P 컴포넌트 → R 컴포넌트로 연결합니다(기존 방향).
+//   - C-S: 클라이언트(R 포트)가 서버(P 포트)의 기능을 호출하여 의존하므로 R 컴포넌트 → P 컴포넌트로 연결합니다.
+const (
+	DirectionProviderToReceiver = "P->R"
+	DirectionReceiverToProvider = "R->P"
+)
+
+// IsClientServer는 InterfaceType 값이 C-S(Client-Server)인지 판단합니다. "C-S", "CS", "ClientServer" 등을 모두 허용합니다.
+func IsClientServer(interfaceType string) bool {
+	switch normalizeHeader(interfaceType) {
+	case "cs", "clientserver":
+		return true
+	}
+	return false
+}
+
+// IsSenderReceiver는 InterfaceType 값이 S-R(Sender-Receiver)인지 판단합니다.
+func IsSenderReceiver(interfaceType string) bool {
+	switch normalizeHeader(interfaceType) {
+	case "sr", "senderreceiver":
+		return true
+	}
+	return false
 }
 
 // asw 파일을 2차원 배열로 변환하여 rows에 저장한 뒤 반환합니다.
-func loadASWRowsFromCSV(filePath string) ([][]string, error) {
+func loadASWRowsFromCSV(filePath string) (rows [][]string, lines []int, err error) {
 	f, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("CSV 파일 열기 실패: %v", err)
+		return nil, nil, fmt.Errorf("CSV 파일 열기 실패: %v", err)
 	}
 	defer f.Close()
 	// CSV 읽기용 리더를 생성합니다.
 	r := csv.NewReader(f)
 	// 각 행의 열 개수가 서로 달라도 허용합니다.
 	r.FieldsPerRecord = -1
-	//asw 파일의 내용을 rows에 저장합니다. rows는 2차원 배열입니다.
-	rows, err := r.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("CSV 행 읽기 실패: %v", err)
+	//asw 파일의 내용을 한 행씩 rows에 저장합니다. rows는 2차원 배열입니다.
+	//빈 줄은 건너뛰고 따옴표 안의 줄바꿈은 한 행으로 합쳐지므로, 각 행이 시작하는 파일의 행 번호를 lines에 함께 저장합니다.
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("CSV 행 읽기 실패: %v", err)
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
 	}
-	return rows, nil
+	return rows, lines, nil
 }
 
-// M3/M4/M6 사용: 각 연결은 독립된 상태로 처리되며, 카운터는 항상 1로 고정됩니다.
-// 여기서는 로드된 rows(2차원 배열)를 읽어 map에 저장한 뒤, 관계 분석을 수행합니다.
-func ExtractDependenciesRawFromASW(filePath string) (map[string][]DependencyInfo, error) {
-	rows, err := loadASWRowsFromCSV(filePath)
-	if err != nil {
-		return nil, err
-	}
+// ASWRow는 asw.csv 한 행에서 분석에 사용하는 값입니다.
+type ASWRow struct {
+	Line          int    // 파일 안의 행 번호(헤더가 1행)
+	Component     string // 컴포넌트 이름
+	ASIL          string // ASIL 등급
+	PortType      string // P / R
+	InterfaceType string // CS / SR
+	DEOP          string // DE/OP 이름
+	Port          string // 포트 이름(선택 열, 없으면 빈 문자열)
+	Interface     string // 인터페이스 이름(선택 열, 없으면 빈 문자열)
+}
 
-	type portInfo struct {
-		component     string	//컴포넌트 이름
-		portType      string	//P 포트인지 R 포트인지 구분
-		interfaceType string	//CS인지 SR인지
-	}
-	//map 생성
-	deMap := make(map[string][]portInfo)
-	for i, row := range rows {
-		// i == 0(헤더 행)이거나 len(row) < 12(열 수가 12 미만)인 경우 해당 행은 건너뜁니다.
-		if i == 0 || len(row) < 12 {
-			continue
-		}
-		component := strings.TrimSpace(row[3])
-		portType := strings.TrimSpace(row[6])
-		interfaceType := strings.TrimSpace(row[8])
-		deOp := strings.TrimSpace(row[11])
-		//데이터 정제 과정에서 해당 정보가 누락된 행은 제거(버림)됩니다.
-		if component == "" || portType == "" || deOp == "" {
-			continue
-		}
-		//Map을 deOp 기준으로 분류하며, 최종 결과는 아래와 같은 형태입니다.
-		// deMap["D1"] = []portInfo{
- 		// 	{component: "EngineCtrl", portType: "P", interfaceType: "IF_CAN"},
-  		// 	{component: "BrakeCtrl",  portType: "R", interfaceType: "IF_CAN"},
-  		// 	{component: "DashBoard",  portType: "R", interfaceType: "IF_CAN"},
-		// }
-		deMap[deOp] = append(deMap[deOp], portInfo{
-			component:     component,
-			portType:      portType,
-			interfaceType: interfaceType,
-		})
-	}
-	//결과 map 생성
-	result := make(map[string][]DependencyInfo)
+// ASWColumnIndex는 헤더에서 찾은 각 열의 위치(0부터 시작)입니다.
+type ASWColumnIndex struct {
+	Component, ASIL, PortType, InterfaceType, DEOP int
+	Port, Interface                                int // 선택 열: 없으면 -1
+}
 
-	// deMap의 각 그룹(카테고리)에서 P/R 인터페이스로 분류합니다.
-	for _, ports := range deMap {
-		var providers []portInfo	//P 인터페이스는 여기에 넣습니다.
-		var receivers []portInfo	//R 인터페이스는 여기에 넣습니다.
-
-		// P/R 분리
-		for _, p := range ports {
-			switch p.portType {
-			case "P":
-				providers = append(providers, p)
-			case "R":
-				receivers = append(receivers, p)
-			}
+// 헤더 비교용 정규화: 대소문자, 공백, 기호를 무시합니다. 예: "DE/OP" → "deop", "Port Type" → "porttype"
+func normalizeHeader(s string) string {
+	s = strings.TrimPrefix(s, "\ufeff") // UTF-8 BOM
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
 		}
+	}
+	return b.String()
+}
 
-		// P 또는 R 중 하나라도 없으면 해당 경우(그룹)는 건너뜁니다.
-		if len(providers) == 0 || len(receivers) == 0 {
-			continue
+// ResolveASWHeader는 헤더 행에서 cols의 별칭과 일치하는 열을 찾습니다.
+// 필수 열(component, asil, port_type, interface_type, de_op) 중 찾지 못한 열이 있으면 모두 나열한 오류를 반환합니다.
+func ResolveASWHeader(header []string, cols Public_data.ASWColumns) (ASWColumnIndex, error) {
+	pos := make(map[string]int)
+	for i, h := range header {
+		key := normalizeHeader(h)
+		if _, ok := pos[key]; !ok && key != "" {
+			pos[key] = i
 		}
+	}
 
-		switch {
-		// P 인터페이스 1개, R 인터페이스 N개 (1→N)
-		case len(providers) == 1 && len(receivers) >= 1:
-			p := providers[0]
-			for _, r := range receivers {
-				if p.component == "" || r.component == "" {
-					continue
-				}
-				if p.component == r.component {
-					// 자기 자신에 대한 의존(자기 의존)은 건너뜁니다.
-					continue
-				}
-				result[p.component] = append(result[p.component], DependencyInfo{
-					To:            r.component,
-					Count:         1,
-					InterfaceType: p.interfaceType,
-				})
-			}
-
-		// P 인터페이스 N개, R 인터페이스 1개 (N→1)
-		case len(receivers) == 1 && len(providers) >= 1:
-			r := receivers[0]
-			for _, p := range providers {
-				if p.component == "" || r.component == "" {
-					continue
-				}
-				if p.component == r.component {
-					continue
-				}
-				result[p.component] = append(result[p.component], DependencyInfo{
-					To:            r.component,
-					Count:         1,
-					InterfaceType: p.interfaceType,
-				})
+	var missing []string
+	lookup := func(aliases []string) int {
+		for _, a := range aliases {
+			if i, ok := pos[normalizeHeader(a)]; ok {
+				return i
 			}
-			//최종 결과는 아래와 같으며, 시작점에서 도착점으로 이어지는 관계가 생성됩니다.
-			// result = map[string][]DependencyInfo{
-  			// 	"EngineCtrl": {
-    		// 		{To:"BrakeCtrl",  Count:1, InterfaceType:"IF_CAN"},
-    		// 		{To:"DashBoard",  Count:1, InterfaceType:"IF_CAN"},
- 			// 	},
-			// }
-		// 이론적으로 P 인터페이스 N개와 R 인터페이스 N개가 동시에 존재하는 경우는 없으므로, 해당 경우는 건너뜁니다.
-		default:
-			continue
 		}
+		return -1
+	}
+	find := func(name string, aliases []string) int {
+		if i := lookup(aliases); i >= 0 {
+			return i
+		}
+		missing = append(missing, fmt.Sprintf("%s(별칭: %s)", name, strings.Join(aliases, ", ")))
+		return -1
 	}
 
-	return result, nil
+	idx := ASWColumnIndex{
+		Component:     find("component", cols.Component),
+		ASIL:          find("asil", cols.ASIL),
+		PortType:      find("port_type", cols.PortType),
+		InterfaceType: find("interface_type", cols.InterfaceType),
+		DEOP:          find("de_op", cols.DEOP),
+		Port:          lookup(cols.Port),
+		Interface:     lookup(cols.Interface),
+	}
+	if len(missing) > 0 {
+		return idx, fmt.Errorf("asw.csv에 필수 열이 없습니다: %s (헤더: %s)", strings.Join(missing, "; "), strings.Join(header, ", "))
+	}
+	return idx, nil
 }
 
-// 이 함수는 위의 함수와 유사하지만, 컴포넌트 간 연결이 여러 개 존재할 경우 Count 값을 누적(증가)합니다. 
-// 반면 위의 함수는 Count를 항상 1로 고정하여 합산(집계)하지 않습니다.
-func ExtractDependenciesAggregatedFromASW(filePath string) (map[string][]DependencyInfo, error) {
-	rows, err := loadASWRowsFromCSV(filePath)
+// LoadASWRows는 asw.csv를 읽어 헤더 이름으로 열을 찾은 뒤, 데이터 행을 ASWRow 목록으로 반환합니다.
+// 열 순서가 바뀌거나 열이 추가되어도 헤더 이름만 맞으면 동일하게 동작합니다.
+// 행의 열 수가 모자라면 없는 값은 빈 문자열로 처리합니다.
+func LoadASWRows(filePath string, cols Public_data.ASWColumns) ([]ASWRow, error) {
+	rows, lines, err := loadASWRowsFromCSV(filePath)
 	if err != nil {
 		return nil, err
 	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("asw.csv가 비어 있습니다: %s", filePath)
+	}
+
+	idx, err := ResolveASWHeader(rows[0], cols)
+	if err != nil {
+		return nil, fmt.Errorf("%v [%s]", err, filePath)
+	}
 
-	type portInfo struct {
-		component     string
-		portType      string
-		interfaceType string
+	cell := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
 	}
 
+	result := make([]ASWRow, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		result = append(result, ASWRow{
+			Line:          lines[i+1],
+			Component:     cell(row, idx.Component),
+			ASIL:          cell(row, idx.ASIL),
+			PortType:      cell(row, idx.PortType),
+			InterfaceType: cell(row, idx.InterfaceType),
+			DEOP:          cell(row, idx.DEOP),
+			Port:          cell(row, idx.Port),
+			Interface:     cell(row, idx.Interface),
+		})
+	}
+	return result, nil
+}
+
+// asw.csv 한 행의 포트 정보입니다. DE/OP 단위로 묶어 P → R 연결을 만드는 데 사용합니다.
+type portInfo struct {
+	component     string //컴포넌트 이름
+	portType      string //P 포트인지 R 포트인지 구분
+	interfaceType string //CS인지 SR인지
+	pairKey       string //N:M 그룹을 이름으로 짝지을 때 사용하는 Port 이름(없으면 Interface 이름)
+}
+
+// groupPortsByDeOp는 rows를 DE/OP 기준으로 분류합니다. 반환되는 deOps는 파일에 처음 나온 순서입니다.
+func groupPortsByDeOp(rows []ASWRow) (map[string][]portInfo, []string) {
+	//Map을 deOp 기준으로 분류하며, 최종 결과는 아래와 같은 형태입니다.
+	// deMap["D1"] = []portInfo{
+	// 	{component: "EngineCtrl", portType: "P", interfaceType: "IF_CAN"},
+	// 	{component: "BrakeCtrl",  portType: "R", interfaceType: "IF_CAN"},
+	// 	{component: "DashBoard",  portType: "R", interfaceType: "IF_CAN"},
+	// }
 	deMap := make(map[string][]portInfo)
-	for i, row := range rows {
-		if i == 0 || len(row) < 12 {
+	var deOps []string
+	// 헤더 행은 LoadASWRows에서 열 위치를 찾는 데 사용되어 rows에는 데이터 행만 있습니다.
+	for _, row := range rows {
+		//데이터 정제 과정에서 해당 정보가 누락된 행은 제거(버림)됩니다.
+		if row.Component == "" || row.PortType == "" || row.DEOP == "" {
 			continue
 		}
-		component := strings.TrimSpace(row[3])
-		portType := strings.TrimSpace(row[6])
-		interfaceType := strings.TrimSpace(row[8])
-		deOp := strings.TrimSpace(row[11])
+		pairKey := row.Port
+		if pairKey == "" {
+			pairKey = row.Interface
+		}
+		if _, ok := deMap[row.DEOP]; !ok {
+			deOps = append(deOps, row.DEOP)
+		}
+		deMap[row.DEOP] = append(deMap[row.DEOP], portInfo{
+			component:     row.Component,
+			portType:      row.PortType,
+			interfaceType: row.InterfaceType,
+			pairKey:       pairKey,
+		})
+	}
+	return deMap, deOps
+}
 
-		if component == "" || portType == "" || deOp == "" {
-			continue
+// link는 의존하는 컴포넌트(from)에서 의존 대상 컴포넌트(to)로 향하는 연결 하나입니다.
+type link struct {
+	from, to      string
+	interfaceType string
+	direction     string
+}
+
+// newLink는 P/R 포트 한 쌍을 인터페이스 타입에 맞는 방향의 연결로 만듭니다.
+// 인터페이스 타입은 P 포트의 값을 우선 사용하고, 비어 있으면 R 포트의 값을 사용합니다.
+func newLink(p, r portInfo) link {
+	ifType := p.interfaceType
+	if ifType == "" {
+		ifType = r.interfaceType
+	}
+	if IsClientServer(ifType) {
+		return link{from: r.component, to: p.component, interfaceType: ifType, direction: DirectionReceiverToProvider}
+	}
+	return link{from: p.component, to: r.component, interfaceType: ifType, direction: DirectionProviderToReceiver}
+}
+
+// connectGroup은 DE/OP 그룹 하나에서 P(제공)와 R(수신)을 짝지어 연결 목록을 만듭니다.
+// 연결 방향은 인터페이스 타입에 따라 정해집니다(newLink 참고).
+//   - P 1개, R N개 (1→N) 또는 P N개, R 1개 (N→1): 모든 P와 R을 연결합니다.
+//   - P N개, R M개 (N:M): policy에 따라 연결하며, nm=true를 반환합니다.
+//
+// 자기 자신에 대한 의존(자기 의존)은 건너뜁니다.
+func connectGroup(ports []portInfo, policy string) (links []link, nm bool) {
+	var providers []portInfo //P 인터페이스는 여기에 넣습니다.
+	var receivers []portInfo //R 인터페이스는 여기에 넣습니다.
+
+	// P/R 분리
+	for _, p := range ports {
+		switch p.portType {
+		case "P":
+			providers = append(providers, p)
+		case "R":
+			receivers = append(receivers, p)
 		}
+	}
 
-		deMap[deOp] = append(deMap[deOp], portInfo{
-			component:     component,
-			portType:      portType,
-			interfaceType: interfaceType,
-		})
+	// P 또는 R 중 하나라도 없으면 해당 경우(그룹)는 건너뜁니다.
+	if len(providers) == 0 || len(receivers) == 0 {
+		return nil, false
 	}
 
-	countMap := make(map[string]map[string]*DependencyInfo)
+	nm = len(providers) > 1 && len(receivers) > 1
+	if nm && policy == Public_data.NMPolicySkip {
+		return nil, true
+	}
 
-	// deOp 단위 집계
-	for _, ports := range deMap {
-		var providers []portInfo
-		var receivers []portInfo
-
-		for _, p := range ports {
-			switch p.portType {
-			case "P":
-				providers = append(providers, p)
-			case "R":
-				receivers = append(receivers, p)
+	for _, p := range providers {
+		for _, r := range receivers {
+			if p.component == r.component {
+				continue
+			}
+			// pair_name: 이름이 같은 P/R만 연결합니다. 이름이 비어 있으면 짝을 지을 수 없습니다.
+			if nm && policy == Public_data.NMPolicyPairByName && (p.pairKey == "" || p.pairKey != r.pairKey) {
+				continue
 			}
+			links = append(links, newLink(p, r))
 		}
+	}
+	return links, nm
+}
 
-		if len(providers) == 0 || len(receivers) == 0 {
-			continue
+// NMDeOps는 rows에서 P와 R이 모두 2개 이상인(N:M) DE/OP 이름을 파일에 나온 순서로 반환합니다.
+func NMDeOps(rows []ASWRow) []string {
+	deMap, deOps := groupPortsByDeOp(rows)
+	var result []string
+	for _, deOp := range deOps {
+		if _, nm := connectGroup(deMap[deOp], Public_data.NMPolicySkip); nm {
+			result = append(result, deOp)
 		}
+	}
+	return result
+}
 
-		switch {
-		// 1 P, N R
-		case len(providers) == 1 && len(receivers) >= 1:
-			p := providers[0]
-			for _, r := range receivers {
-				if p.component == "" || r.component == "" {
-					continue
-				}
-				if p.component == r.component {
-					continue
-				}
+// M3/M4/M6 사용: 각 연결은 독립된 상태로 처리되며, 카운터는 항상 1로 고정됩니다.
+// 여기서는 헤더 이름으로 읽은 rows를 DE/OP 기준으로 묶은 뒤, 관계 분석을 수행합니다.
+// policy는 N:M 그룹의 처리 방식(Public_data.NMPolicy*)입니다.
+func ExtractDependenciesRawFromASW(filePath string, cols Public_data.ASWColumns, policy string) (map[string][]DependencyInfo, error) {
+	rows, err := LoadASWRows(filePath, cols)
+	if err != nil {
+		return nil, err
+	}
+	return rawDependencies(rows, policy), nil
+}
 
-				from := p.component
-				to := r.component
+func rawDependencies(rows []ASWRow, policy string) map[string][]DependencyInfo {
+	deMap, deOps := groupPortsByDeOp(rows)
 
-				if _, ok := countMap[from]; !ok {
-					countMap[from] = make(map[string]*DependencyInfo)
-				}
-				if existing, ok := countMap[from][to]; ok {
-					existing.Count++
-				} else {
-					countMap[from][to] = &DependencyInfo{
-						To:            to,
-						Count:         1,
-						InterfaceType: p.interfaceType,
-					}
-				}
-			}
+	//결과 map 생성
+	result := make(map[string][]DependencyInfo)
+	for _, deOp := range deOps {
+		links, _ := connectGroup(deMap[deOp], policy)
+		for _, l := range links {
+			result[l.from] = append(result[l.from], DependencyInfo{
+				To:            l.to,
+				Count:         1,
+				InterfaceType: l.interfaceType,
+				Direction:     l.direction,
+			})
+		}
+	}
+	//최종 결과는 아래와 같으며, 시작점에서 도착점으로 이어지는 관계가 생성됩니다.
+	// result = map[string][]DependencyInfo{
+	// 	"EngineCtrl": {
+	// 		{To:"BrakeCtrl",  Count:1, InterfaceType:"S-R", Direction:"P->R"},
+	// 		{To:"DashBoard",  Count:1, InterfaceType:"S-R", Direction:"P->R"},
+	// 	},
+	// }
+	return result
+}
 
-		// N P, 1 R
-		case len(receivers) == 1 && len(providers) >= 1:
-			r := receivers[0]
-			for _, p := range providers {
-				if p.component == "" || r.component == "" {
-					continue
-				}
-				if p.component == r.component {
-					continue
-				}
+// 이 함수는 위의 함수와 유사하지만, 컴포넌트 간 연결이 여러 개 존재할 경우 Count 값을 누적(증가)합니다. 
+// 반면 위의 함수는 Count를 항상 1로 고정하여 합산(집계)하지 않습니다.
+// 집계는 (도착 컴포넌트, InterfaceType, Direction)별로 하므로, 같은 컴포넌트 쌍의 S-R과 C-S 연결은 별도 항목이 됩니다.
+func ExtractDependenciesAggregatedFromASW(filePath string, cols Public_data.ASWColumns, policy string) (map[string][]DependencyInfo, error) {
+	rows, err := LoadASWRows(filePath, cols)
+	if err != nil {
+		return nil, err
+	}
+	return aggregatedDependencies(rows, policy), nil
+}
 
-				from := p.component
-				to := r.component
+func aggregatedDependencies(rows []ASWRow, policy string) map[string][]DependencyInfo {
+	deMap, deOps := groupPortsByDeOp(rows)
 
-				if _, ok := countMap[from]; !ok {
-					countMap[from] = make(map[string]*DependencyInfo)
-				}
-				if existing, ok := countMap[from][to]; ok {
-					existing.Count++
-				} else {
-					countMap[from][to] = &DependencyInfo{
-						To:            to,
-						Count:         1,
-						InterfaceType: p.interfaceType,
-					}
+	// from → (to, InterfaceType, Direction) → 집계. 같은 컴포넌트 쌍이라도 S-R과 C-S 연결은 따로 셉니다.
+	type depKey struct {
+		to, interfaceType, direction string
+	}
+	countMap := make(map[string]map[depKey]*DependencyInfo)
+
+	// deOp 단위 집계
+	for _, deOp := range deOps {
+		links, _ := connectGroup(deMap[deOp], policy)
+		for _, l := range links {
+			from := l.from
+			key := depKey{to: l.to, interfaceType: l.interfaceType, direction: l.direction}
+
+			if _, ok := countMap[from]; !ok {
+				countMap[from] = make(map[depKey]*DependencyInfo)
+			}
+			if existing, ok := countMap[from][key]; ok {
+				existing.Count++
+			} else {
+				countMap[from][key] = &DependencyInfo{
+					To:            l.to,
+					Count:         1,
+					InterfaceType: l.interfaceType,
+					Direction:     l.direction,
 				}
 			}
-
-		default:
-			continue
 		}
 	}
 
@@ -271,24 +414,34 @@ func ExtractDependenciesAggregatedFromASW(filePath string) (map[string][]Depende
 		for _, dep := range depMap {
 			result[from] = append(result[from], *dep)
 		}
+		deps := result[from]
+		sort.Slice(deps, func(i, j int) bool {
+			if deps[i].To != deps[j].To {
+				return deps[i].To < deps[j].To
+			}
+			return deps[i].InterfaceType < deps[j].InterfaceType
+		})
 	}
 
-	return result, nil
+	return result
 }
 
-// AnalyzeSWCDependencies는 ASW.csv 파일의 내용을 LDI.xml 파일로 변환합니다.
-func AnalyzeSWCDependencies(filePath string) {
+// AnalyzeSWCDependencies는 ASW.csv 파일의 내용을 LDI 문서로 변환합니다.
+// N:M 그룹이 있으면 해당 DE/OP 목록과 적용한 정책을 경고로 출력합니다.
+func AnalyzeSWCDependencies(filePath string, cols Public_data.ASWColumns, policy string) (*LDI_Model.Document, error) {
 	if strings.TrimSpace(filePath) == "" {
-		//Public_data.ConnectorFilePath가 비어 있으면 실패합니다.
-		fmt.Println("의존 관계 분석 실패: asw.csv 경로가 비어 있습니다.")
-		return
+		//RunConfig.ConnectorFilePath가 비어 있으면 실패합니다.
+		return nil, fmt.Errorf("의존 관계 분석 실패: asw.csv 경로가 비어 있습니다.")
 	}
 
-	dependencies, err := ExtractDependenciesAggregatedFromASW(filePath)
+	rows, err := LoadASWRows(filePath, cols)
 	if err != nil {
-		fmt.Println("의존 관계 분석 실패:", err)
-		return
+		return nil, fmt.Errorf("의존 관계 분석 실패: %v", err)
+	}
+	if nm := NMDeOps(rows); len(nm) > 0 {
+		fmt.Printf("⚠️ P/R이 N:M인 DE/OP %d개 (정책: %s): %s\n", len(nm), policy, strings.Join(nm, ", "))
 	}
+	dependencies := aggregatedDependencies(rows, policy)
 
 	// 기존에 ExtractDependenciesAggregatedFromASW로 집계(aggregation)된 정보를 분해합니다.
 	// depMap은 의존 관계(누가 누구를 가리키는지)만 저장합니다.
@@ -297,21 +450,25 @@ func AnalyzeSWCDependencies(filePath string) {
 	strengthMap := make(map[string]map[string]int)
 	
 	// 여기에서 ExtractDependenciesAggregatedFromASW로 집계된 결과를 분해합니다.
+	// 같은 컴포넌트 쌍의 S-R/C-S 연결은 따로 집계되어 있으므로 strength는 합산합니다.
 	for from, deps := range dependencies {
 		for _, dep := range deps {
-			depMap[from] = append(depMap[from], dep.To)
 			if strengthMap[from] == nil {
 				strengthMap[from] = make(map[string]int)
 			}
-			strengthMap[from][dep.To] = dep.Count
+			if _, ok := strengthMap[from][dep.To]; !ok {
+				depMap[from] = append(depMap[from], dep.To)
+			}
+			strengthMap[from][dep.To] += dep.Count
 		}
 	}
 
 	// LDI_Create의 LDIXML 생성 함수를 호출하여 LDI를 생성합니다.
-	if err := LDI_Create.GenerateLDIXml(depMap, strengthMap); err != nil {
-		fmt.Println("의존관계 분석 실패:", err)
-		return
+	doc, err := LDI_Create.GenerateLDIXml(depMap, strengthMap)
+	if err != nil {
+		return nil, fmt.Errorf("의존관계 분석 실패: %v", err)
 	}
 
 	fmt.Println("의존관계 분석 완료.")
+	return doc, nil
 }
@@ -0,0 +1,36 @@
+package SWC_Dependence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"FCU_Tools/Public_data"
+)
+
+func TestLoadASWRowsLineNumbers(t *testing.T) {
+	// 3행은 빈 줄이고, 4행의 Port 값은 따옴표 안의 줄바꿈으로 5행까지 이어집니다.
+	data := "Component,ASIL,PortType,InterfaceType,DE/OP,Port\n" +
+		"A,QM,P,SR,D1,P1\n" +
+		"\n" +
+		"B,QM,R,SR,D1,\"R1\n(multi-line)\"\n" +
+		"C,QM,R,SR,D1,R2\n"
+	path := filepath.Join(t.TempDir(), "asw.csv")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := LoadASWRows(path, Public_data.DefaultASWColumns)
+	if err != nil {
+		t.Fatalf("LoadASWRows: %v", err)
+	}
+	want := map[string]int{"A": 2, "B": 4, "C": 6}
+	if len(rows) != len(want) {
+		t.Fatalf("행 수 = %d, want %d", len(rows), len(want))
+	}
+	for _, row := range rows {
+		if row.Line != want[row.Component] {
+			t.Errorf("%s의 행 번호 = %d, want %d", row.Component, row.Line, want[row.Component])
+		}
+	}
+}
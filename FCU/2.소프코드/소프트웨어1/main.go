@@ -1,39 +1,72 @@
 package main
 
 import (
-	"FCU_Tools/M1"
-	"FCU_Tools/M2"
-	"FCU_Tools/M3"
-	"FCU_Tools/M4"
-	"FCU_Tools/M5"
-	"FCU_Tools/M6"
+	"flag"
+	"fmt"
+	"os"
+
+	_ "FCU_Tools/All_Metrics"
+	"FCU_Tools/LDI_Create"
+	"FCU_Tools/LDI_Model"
+	"FCU_Tools/Metric_Registry"
 	"FCU_Tools/Public_data"
-	"FCU_Tools/SWC_Dependence"
 )
 
 func main() {
-	/***************SWC간 의존관계***************/
-	// 1) Output 폴더를 초기화 (여기에서도 asw.csv가 어디는지를 물어보고 경로를 Public_data.ConnectorFilePath에 저장함)
-	Public_data.InitOutputDirectory()
-
-	// 2) Public_data.ConnectorFilePath에 저장된 asw.csv를 기반으로 swc간 의존관계를 분석함.
-	SWC_Dependence.AnalyzeSWCDependencies(Public_data.ConnectorFilePath)
-
-	/***************M1지표***************/
-	M1main.M1_main()
-
-	/***************M2지표***************/
-	M2main.M2_main()
-
-	/***************M3지표***************/
-	M3main.M3_main()
+	configPath := flag.String("config", "", "프로젝트 설정 파일(JSON). 지정하지 않으면 입력 경로를 터미널에서 입력받습니다.")
+	flag.Parse()
+	os.Exit(run(*configPath))
+}
 
-	/***************M4지표***************/
-	M4main.M4_main()
+// run은 configPath의 설정(비어 있으면 대화형 입력)으로 전체 분석을 수행하고 프로세스 종료 코드를 반환합니다.
+//   - 0: 모든 지표 성공
+//   - 1: 실행 불가(출력 디렉터리/입력 경로 오류, 결과 저장 실패 등)
+//   - 2: 결과는 저장했지만 일부 지표가 실패함
+func run(configPath string) int {
+	/***************SWC간 의존관계***************/
+	// 1) 설정 파일(없으면 현재 디렉터리 기준 기본값)로 실행 설정을 만들고 Output 폴더를 초기화합니다.
+	//    (설정 파일에 asw.csv 경로가 없으면 여기에서 물어보고 경로를 cfg.ConnectorFilePath에 저장함)
+	var cfg *Public_data.RunConfig
+	var err error
+	if configPath != "" {
+		cfg, err = Public_data.LoadConfigFile(configPath)
+	} else {
+		cfg, err = Public_data.NewRunConfigFromCwd()
+	}
+	if err != nil {
+		fmt.Println("❌", err)
+		return Metric_Registry.ExitError
+	}
+	if err := Metric_Registry.Default.Configure(cfg.EnabledMetrics, cfg.DisabledMetrics); err != nil {
+		fmt.Println("❌ 지표 설정 오류:", err)
+		return Metric_Registry.ExitError
+	}
+	if err := cfg.InitOutputDirectory(); err != nil {
+		fmt.Println("❌", err)
+		return Metric_Registry.ExitError
+	}
+	if cfg.ConnectorFilePath == "" {
+		if err := cfg.InitConnectorFilePathFromUser(); err != nil {
+			fmt.Println("❌ asw.csv 경로 설정에 실패했습니다:", err)
+			return Metric_Registry.ExitError
+		}
+	}
 
-	/***************M5지표***************/
-	M5main.M5_main()
+	/***************SWC 의존관계 및 M1~M6지표***************/
+	// 2) 등록된 지표(SWC, M1~M6)를 의존관계 순서대로 실행하고, 각 결과를 주 LDI에 병합합니다.
+	mainLDI := LDI_Model.New()
+	report, err := Metric_Registry.Default.Run(cfg, mainLDI, nil)
+	if err != nil {
+		fmt.Println("❌ 지표 실행 계획 실패:", err)
+		return Metric_Registry.ExitError
+	}
+	report.Print(os.Stdout)
 
-	/***************M6지표***************/
-	M6main.M6_main()
+	/***************결과 저장***************/
+	// 모든 지표가 병합된 주 LDI를 result.ldi.xml로 한 번만 기록합니다.
+	if err := LDI_Create.SaveMainLDI(cfg, mainLDI); err != nil {
+		fmt.Println("❌ 주 LDI 저장 실패:", err)
+		return Metric_Registry.ExitError
+	}
+	return report.ExitCode()
 }
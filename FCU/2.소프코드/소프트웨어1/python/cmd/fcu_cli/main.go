@@ -5,22 +5,41 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"FCU_Tools/M1"
-	"FCU_Tools/M1/M1_Public_Data"
-	"FCU_Tools/M2"
-	"FCU_Tools/M3"
-	"FCU_Tools/M4"
-	"FCU_Tools/M5"
-	"FCU_Tools/M6"
+	_ "FCU_Tools/All_Metrics"
+	"FCU_Tools/Input_Validation"
+	"FCU_Tools/LDI_Create"
+	"FCU_Tools/LDI_Model"
+	"FCU_Tools/Metric_Registry"
 	"FCU_Tools/Public_data"
-	"FCU_Tools/SWC_Dependence"
 )
 
 func main() {
+	// "fcu_cli validate ..."는 분석 없이 입력 파일만 검사합니다.
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", "", "project config file (JSON); flags below override its values")
 	connectorDir := flag.String("connector-dir", "", "input directory containing asw.csv")
 	modelDir := flag.String("model-dir", "", "model directory for M1 analysis")
 	quiet := flag.Bool("quiet", false, "print only final output path")
+	metrics := flag.String("metrics", "", "comma-separated metrics to run (default: all registered)")
+	skip := flag.String("skip", "", "comma-separated metrics to disable")
+	workDir := flag.String("work-dir", "", "directory for intermediate files (default: current directory)")
+	outputDir := flag.String("output-dir", "", "directory for result.ldi.xml (default: <work-dir>/Output)")
+	m1MaxDepth := flag.Int("m1-max-depth", -1, "maximum M1 hierarchy depth (0: unlimited, default: config or 3)")
+	m1Workers := flag.Int("m1-workers", -1, "number of models analyzed in parallel by M1 (0: number of CPUs)")
+	m1Extract := flag.Bool("m1-extract", false, "extract .slx files to <work-dir>/M1/build for debugging (default: read in memory)")
+	m1DumpTxt := flag.Bool("m1-dump-txt", false, "write the analyzed M1 block tree as text to <work-dir>/M1/output/txt")
+	m1DumpJSON := flag.Bool("m1-dump-json", false, "write the analyzed M1 block tree as JSON to <work-dir>/M1/output/txt")
+	m1VirtualPorts := flag.Bool("m1-virtual-ports", false, "count direct block-to-block lines as virtual ports from M1 level 2 (same as virtual_ports in m1.levels)")
+	m1Include := flag.String("m1-include", "", "comma-separated glob patterns of model files to analyze, relative to model-dir (e.g. **/*.slx; default: <Name>/<Name>.slx)")
+	m1Exclude := flag.String("m1-exclude", "", "comma-separated glob patterns of model files to skip, relative to model-dir")
+	m1ConsistencyProps := flag.Bool("m1-consistency-props", false, "record asw.csv component/model mismatches as m1.consistency properties in the LDI")
+	nmPolicy := flag.String("nm-policy", "", "N:M provider/receiver policy in asw.csv: cross, pair_name or skip (default: cross)")
+	validate := flag.Bool("validate", false, "validate input files before analysis and stop on errors")
 	flag.Parse()
 
 	outputWriter := os.Stdout
@@ -32,44 +51,224 @@ func main() {
 		}
 	}
 
-	if *connectorDir == "" {
+	cfg, err := newRunConfig(*configPath, *workDir, *outputDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(Metric_Registry.ExitError)
+	}
+	if *connectorDir != "" {
+		if err := cfg.SetInputDir(*connectorDir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(Metric_Registry.ExitError)
+		}
+	}
+	if *modelDir != "" {
+		cfg.ModelDir = *modelDir
+	}
+	if *metrics != "" {
+		cfg.EnabledMetrics = splitList(*metrics)
+	}
+	cfg.DisabledMetrics = append(cfg.DisabledMetrics, splitList(*skip)...)
+	if *m1MaxDepth >= 0 {
+		cfg.M1MaxDepth = *m1MaxDepth
+	}
+	if *m1Workers >= 0 {
+		cfg.M1Workers = *m1Workers
+	}
+	if *m1Extract {
+		cfg.M1ExtractToDisk = true
+	}
+	if *m1DumpTxt {
+		cfg.M1DumpTxt = true
+	}
+	if *m1DumpJSON {
+		cfg.M1DumpJSON = true
+	}
+	if *m1VirtualPorts {
+		cfg.M1LevelRules = cfg.M1LevelRules.WithVirtualPorts(2)
+	}
+	if *m1Include != "" {
+		cfg.M1Discovery.Include = splitList(*m1Include)
+	}
+	cfg.M1Discovery.Exclude = append(cfg.M1Discovery.Exclude, splitList(*m1Exclude)...)
+	if *m1ConsistencyProps {
+		cfg.M1ConsistencyProperties = true
+	}
+	if *nmPolicy != "" {
+		cfg.NMPolicy = *nmPolicy
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(Metric_Registry.ExitError)
+	}
+
+	if cfg.ConnectorFilePath == "" {
 		fmt.Fprintln(os.Stderr, "connector-dir is required")
-		os.Exit(1)
+		os.Exit(Metric_Registry.ExitError)
 	}
-	if *modelDir == "" {
+	if cfg.ModelDir == "" {
 		fmt.Fprintln(os.Stderr, "model-dir is required")
-		os.Exit(1)
+		os.Exit(Metric_Registry.ExitError)
 	}
-	if err := ensureDirExists(*modelDir); err != nil {
+	if err := ensureDirExists(cfg.ModelDir); err != nil {
 		fmt.Fprintln(os.Stderr, "model-dir error:", err)
-		os.Exit(1)
+		os.Exit(Metric_Registry.ExitError)
 	}
 
-	if err := Public_data.InitOutputDirectoryWithConnectorDir(*connectorDir); err != nil {
+	if *validate {
+		rep := Input_Validation.ValidateInputs(cfg)
+		rep.Print(os.Stderr)
+		if rep.HasErrors() {
+			os.Exit(Metric_Registry.ExitError)
+		}
+	}
+
+	registry := Metric_Registry.Default
+	if err := registry.Configure(cfg.EnabledMetrics, cfg.DisabledMetrics); err != nil {
+		fmt.Fprintln(os.Stderr, "metrics error:", err)
+		os.Exit(Metric_Registry.ExitError)
+	}
+
+	if err := cfg.InitOutputDirectory(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(Metric_Registry.ExitError)
 	}
 	printProgress(outputWriter, 10)
 
-	SWC_Dependence.AnalyzeSWCDependencies(Public_data.ConnectorFilePath)
-	printProgress(outputWriter, 20)
-
-	M1_Public_Data.SrcPath = *modelDir
-	M1main.M1_main()
-	printProgress(outputWriter, 40)
-	M2main.M2_main()
-	printProgress(outputWriter, 55)
-	M3main.M3_main()
-	printProgress(outputWriter, 70)
-	M4main.M4_main()
-	printProgress(outputWriter, 80)
-	M5main.M5_main()
-	printProgress(outputWriter, 90)
-	M6main.M6_main()
+	// 등록된 지표를 의존관계 순서대로 실행하며, 지표 하나가 끝날 때마다 진행률(10~95)을 출력합니다.
+	mainLDI := LDI_Model.New()
+	report, err := registry.Run(cfg, mainLDI, func(done, total int, res Metric_Registry.Result) {
+		printProgress(outputWriter, 10+85*done/total)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(Metric_Registry.ExitError)
+	}
+	report.Print(os.Stdout)
+
+	if err := LDI_Create.SaveMainLDI(cfg, mainLDI); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(Metric_Registry.ExitError)
+	}
+
+	// 실패한 지표는 --quiet에서도 보이도록 stderr에 "WARNING:" 줄로 출력합니다.
+	// GUI는 "WARNING:"으로 시작하는 줄만 경고로 읽으므로, 여러 오류를 모은 Err는 오류마다 한 줄씩 출력합니다.
+	// 결과 경로는 GUI가 마지막 줄로 읽으므로 항상 가장 마지막에 출력합니다.
+	for _, res := range report.Results {
+		if res.Status == Metric_Registry.StatusOK {
+			continue
+		}
+		for _, msg := range errorLines(res.Err) {
+			fmt.Fprintf(os.Stderr, "WARNING:%s %s: %s\n", res.Name, res.Status, msg)
+		}
+	}
 	printProgress(outputWriter, 100)
 
-	outputPath := filepath.Join(Public_data.OutputDir, "result.ldi.xml")
-	fprintln(outputWriter, outputPath)
+	fprintln(outputWriter, cfg.ResultPath())
+	os.Exit(report.ExitCode())
+}
+
+// runValidate는 validate 하위 명령을 처리합니다.
+// 입력 파일의 문제를 파일/행 번호와 함께 출력하고, 오류가 하나라도 있으면 1을 반환합니다.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configPath := fs.String("config", "", "project config file (JSON); flags below override its values")
+	connectorDir := fs.String("connector-dir", "", "input directory containing asw.csv")
+	fs.Parse(args)
+
+	cfg, err := newRunConfig(*configPath, "", "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return Metric_Registry.ExitError
+	}
+	if *connectorDir != "" {
+		if err := cfg.SetInputDir(*connectorDir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return Metric_Registry.ExitError
+		}
+	}
+	if cfg.ConnectorFilePath == "" {
+		fmt.Fprintln(os.Stderr, "connector-dir is required")
+		return Metric_Registry.ExitError
+	}
+
+	rep := Input_Validation.ValidateInputs(cfg)
+	rep.Print(os.Stdout)
+	if rep.HasErrors() {
+		return Metric_Registry.ExitError
+	}
+	return Metric_Registry.ExitOK
+}
+
+// newRunConfig는 --config 설정 파일(없으면 기본값)과 --work-dir/--output-dir 플래그로 실행 설정을 만듭니다.
+// 작업 디렉터리가 어디에도 지정되지 않으면 현재 디렉터리 기준입니다.
+func newRunConfig(configPath, workDir, outputDir string) (*Public_data.RunConfig, error) {
+	var cfg *Public_data.RunConfig
+	var err error
+	if configPath != "" {
+		cfg, err = Public_data.LoadConfigFile(configPath)
+	} else {
+		cfg, err = Public_data.NewRunConfigFromCwd()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if workDir != "" {
+		abs, err := filepath.Abs(workDir)
+		if err != nil {
+			return nil, fmt.Errorf("work-dir error: %v", err)
+		}
+		// 출력 경로를 따로 지정하지 않았다면 새 작업 디렉터리 아래의 Output을 사용합니다.
+		if outputDir == "" && cfg.OutputDir == filepath.Join(cfg.WorkDir, "Output") {
+			cfg.OutputDir = filepath.Join(abs, "Output")
+		}
+		cfg.WorkDir = abs
+	}
+	if outputDir != "" {
+		abs, err := filepath.Abs(outputDir)
+		if err != nil {
+			return nil, fmt.Errorf("output-dir error: %v", err)
+		}
+		cfg.OutputDir = abs
+	}
+	return cfg, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// errorLines는 errors.Join으로 모은 오류를 (중첩된 것까지) 하나씩 펼쳐, 오류마다 한 줄짜리 메시지로 반환합니다.
+// 펼칠 수 없는 오류 안의 줄바꿈은 "; "로 바꿉니다.
+func errorLines(err error) []string {
+	if err == nil {
+		return []string{"<nil>"}
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var lines []string
+		for _, e := range joined.Unwrap() {
+			if e != nil {
+				lines = append(lines, errorLines(e)...)
+			}
+		}
+		if len(lines) > 0 {
+			return lines
+		}
+	}
+	var parts []string
+	for _, l := range strings.Split(err.Error(), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			parts = append(parts, l)
+		}
+	}
+	return []string{strings.Join(parts, "; ")}
 }
 
 func ensureDirExists(path string) error {
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestErrorLines(t *testing.T) {
+	stage := errors.Join(errors.New("모델 A 분석 실패"), errors.New("모델 B 분석 실패"))
+	err := errors.Join(stage, fmt.Errorf("LDI 작성 실패:\n%w", errors.New("권한 없음")))
+
+	want := []string{"모델 A 분석 실패", "모델 B 분석 실패", "LDI 작성 실패:; 권한 없음"}
+	if got := errorLines(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("errorLines = %q, want %q", got, want)
+	}
+}
@@ -30,6 +30,11 @@ STATUS_IDLE = "대기"
 STATUS_RUNNING = "실행 중... 시간이 걸릴 수 있습니다"
 STATUS_DONE = "완료"
 STATUS_FAILED = "실패"
+STATUS_PARTIAL = "완료 (일부 지표 실패)"
+
+# fcu_cli 종료 코드
+EXIT_OK = 0
+EXIT_PARTIAL = 2
 
 
 def validate_dir(path):
@@ -78,6 +83,7 @@ def run_pipeline(connector_dir, model_dir, on_progress):
     ]
     output_path = ""
     lines = []
+    warnings = []
 
     process = subprocess.Popen(
         cmd,
@@ -106,17 +112,20 @@ def run_pipeline(connector_dir, model_dir, on_progress):
             if percent is not None:
                 on_progress(percent)
             continue
+        if line.startswith("WARNING:"):
+            warnings.append(line.split(":", 1)[1].strip())
+            continue
         output_path = line
 
     process.wait()
-    if process.returncode != 0:
+    if process.returncode not in (EXIT_OK, EXIT_PARTIAL):
         err = "\n".join(lines[-5:]) if lines else "실행 실패"
         raise RuntimeError(err)
 
     if not output_path:
         raise RuntimeError("출력 경로를 가져오지 못했습니다")
 
-    return output_path
+    return output_path, warnings
 
 
 def main():
@@ -186,13 +195,18 @@ def main():
 
         def worker():
             try:
-                output_path = run_pipeline(
+                output_path, warnings = run_pipeline(
                     connector_dir,
                     model_dir,
                     lambda p: root.after(0, lambda: report_progress(p)),
                 )
                 root.after(0, lambda: output_var.set(output_path))
-                root.after(0, lambda: status_var.set(STATUS_DONE))
+                if warnings:
+                    message = "일부 지표가 실패했습니다:\n" + "\n".join(warnings)
+                    root.after(0, lambda: messagebox.showwarning("부분 실패", message))
+                    root.after(0, lambda: status_var.set(STATUS_PARTIAL))
+                else:
+                    root.after(0, lambda: status_var.set(STATUS_DONE))
             except Exception as exc:
                 root.after(0, lambda: show_error("실행 실패", str(exc)))
                 root.after(0, lambda: status_var.set(STATUS_FAILED))